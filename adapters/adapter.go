@@ -3,6 +3,7 @@ package adapters
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"net/http"
 )
 
@@ -23,6 +24,9 @@ type Adapter interface {
 	// BatchInsertCopy executes a batch insert sql into a table unsing copy and given params
 	BatchInsertCopy(dbname, schema, table string, keys []string, params ...interface{}) (sc Scanner)
 	BatchInsertCopyCtx(ctx context.Context, dbname, schema, table string, keys []string, params ...interface{}) (sc Scanner)
+	// BatchUpsertCopyCtx executes a batch insert using copy into a temporary
+	// table, inserted into the table with the onConflict clause
+	BatchUpsertCopyCtx(ctx context.Context, dbname, schema, table string, keys []string, onConflict string, params ...interface{}) (sc Scanner)
 
 	// CountByRequest implements COUNT(fields) OPERTATION
 	//
@@ -60,7 +64,7 @@ type Adapter interface {
 
 	FieldsPermissions(r *http.Request, table string, op string) (fields []string, err error)
 	GetScript(verb, folder, scriptName string) (script string, err error)
-	GroupByClause(r *http.Request) (groupBySQL string)
+	GroupByClause(r *http.Request, initialPlaceholderID int) (groupBySQL string, values []interface{})
 
 	Insert(SQL string, params ...interface{}) (sc Scanner)
 	InsertCtx(ctx context.Context, SQL string, params ...interface{}) (sc Scanner)
@@ -68,6 +72,75 @@ type Adapter interface {
 	InsertWithTransaction(tx *sql.Tx, SQL string, params ...interface{}) (sc Scanner)
 	InsertSQL(database string, schema string, table string, names string, placeholders string) string
 	JoinByRequest(r *http.Request) (values []string, err error)
+
+	// BucketByRequest returns the time bucket of `_bucket` to select and
+	// group by
+	BucketByRequest(ctx context.Context, r *http.Request, initialPlaceholderID int) (field, bucketSQL string, values []interface{}, err error)
+	// GapFillByRequest adds the empty buckets to a time bucketed query
+	GapFillByRequest(r *http.Request, SQL string, initialPlaceholderID int) (gapFillSQL string, values []interface{}, err error)
+
+	// TotalCountCtx returns the total of rows of a query, counted (exact)
+	// or estimated by the planner (planned)
+	TotalCountCtx(ctx context.Context, count string, SQL string, params ...interface{}) (total int64, err error)
+	// TableCountEstimateCtx returns the number of rows of a table kept on
+	// the statistics of the planner, -1 when there is none
+	TableCountEstimateCtx(ctx context.Context, schema, table string) (total int64, err error)
+
+	// TextSearchByRequest returns the rank to order by and the headlines to
+	// select of full text search filters, requested with `_rank` and `_headline`
+	TextSearchByRequest(r *http.Request, initialPlaceholderID int) (rank string, headlines []string, values []interface{}, err error)
+
+	// ExpandByRequest returns the select expressions that embed the rows
+	// related through foreign keys requested with the `_expand` query string
+	ExpandByRequest(ctx context.Context, r *http.Request, schema, table string) (expandSyntax []string, err error)
+
+	// KeysetByRequest returns the condition of keyset (cursor) pagination
+	// requested with the `_after` or `_before` query strings
+	KeysetByRequest(r *http.Request, initialPlaceholderID int) (keysetSyntax string, values []interface{}, err error)
+	// KeysetPage prepares the query result of a keyset paginated request
+	// and returns the cursors to the next and previous pages
+	KeysetPage(r *http.Request, body []byte) (page []byte, next, prev string, err error)
+
+	// PrimaryKeyCtx returns the columns of the primary key of a table in
+	// the order of the key
+	PrimaryKeyCtx(ctx context.Context, schema, table string) (columns []string, err error)
+	// PrimaryKeyWhereCtx returns the condition that matches the row of the
+	// primary key values sent on the path, separated by commas
+	PrimaryKeyWhereCtx(ctx context.Context, schema, table, pk string, initialPlaceholderID int) (whereSyntax string, values []interface{}, err error)
+	// SoftDeleteWhere returns the condition that excludes the soft deleted
+	// rows of a table, empty when it has no soft_delete column
+	SoftDeleteWhere(table string) (whereSyntax string)
+	// SoftDeleteSQL returns the update that marks the rows of a table as
+	// deleted, empty when it has no soft_delete column
+	SoftDeleteSQL(database, schema, table string) (sql string)
+	// WithDeletedByRequest returns true when the soft deleted rows are
+	// requested with `_with_deleted=true` and permitted
+	WithDeletedByRequest(r *http.Request, table string) (withDeleted bool, err error)
+	// IfMatchByRequest returns the condition that matches the row only when
+	// its ETag is one of the If-Match header, empty when it is not sent
+	IfMatchByRequest(r *http.Request, table string, initialPlaceholderID int) (whereSyntax string, values []interface{}, err error)
+
+	// FunctionArgsByRequest returns the named arguments of a function
+	// call, from the json body of a POST or the query string of a GET
+	FunctionArgsByRequest(r *http.Request) (args map[string]interface{}, err error)
+	// FunctionPermissions returns true when the function is listed with
+	// the execute permission and is not in a system schema
+	FunctionPermissions(schema, function string) (access bool)
+	// CallFunctionCtx calls the overload of a function taking the named
+	// arguments and returns its result as json, volatile functions are not
+	// called when readOnly is true
+	CallFunctionCtx(ctx context.Context, schema, name string, args map[string]interface{}, readOnly bool) (sc Scanner)
+
+	// ParseBulkUpdateRequest returns the rows of a bulk update, objects
+	// holding the columns of the primary key and the ones to set
+	ParseBulkUpdateRequest(r *http.Request) (rows []map[string]json.RawMessage, err error)
+	// BulkUpdateCtx updates each row by its primary key with its own values
+	// in one transaction, the placeholders of where start at 2
+	BulkUpdateCtx(ctx context.Context, database, schema, table string, rows []map[string]json.RawMessage, where string, whereValues []interface{}, returning string) (sc Scanner)
+	// OnConflictByRequest returns the ON CONFLICT clause of an upsert
+	// requested with `_on_conflict` or the resolution of the Prefer header
+	OnConflictByRequest(ctx context.Context, r *http.Request, schema, table, resolution, names string) (onConflictSyntax string, err error)
+
 	OrderByRequest(r *http.Request) (values string, err error)
 	PaginateIfPossible(r *http.Request) (paginatedQuery string, err error)
 	ParseBatchInsertRequest(r *http.Request) (colsName string, colsValue string, values []interface{}, err error)
@@ -78,6 +151,15 @@ type Adapter interface {
 	QueryCtx(ctx context.Context, SQL string, params ...interface{}) (sc Scanner)
 	QueryCount(SQL string, params ...interface{}) (sc Scanner)
 	QueryCountCtx(ctx context.Context, SQL string, params ...interface{}) (sc Scanner)
+	// QueryStreamCtx calls fn with the json object of each row of a query
+	// as it is read, without buffering the result
+	QueryStreamCtx(ctx context.Context, SQL string, fn func(row []byte) error, params ...interface{}) (err error)
+	// QueryRowsCtx calls fn with the columns of a query and then with the
+	// text of the fields of each row as it is read
+	QueryRowsCtx(ctx context.Context, SQL string, fn func(row []string) error, params ...interface{}) (err error)
+	// QueryValuesCtx calls columns with the columns of a query and then row
+	// with the values of each row as they are read
+	QueryValuesCtx(ctx context.Context, SQL string, columns func(cols []Column) error, row func(values []interface{}) error, params ...interface{}) (err error)
 
 	ReturningByRequest(r *http.Request) (returningSyntax string, err error)
 	SchemaClause(req *http.Request) (query string, hasCount bool)
@@ -85,7 +167,7 @@ type Adapter interface {
 	SchemaTablesClause() (query string)
 	SchemaTablesOrderBy(order string) (orderBy string)
 	SchemaTablesWhere(requestWhere string) (whereSyntax string)
-	SelectFields(fields []string) (sql string, err error)
+	SelectFields(fields []string, initialPlaceholderID int) (sql string, values []interface{}, err error)
 	SelectSQL(selectStr string, database string, schema string, table string) string
 	SetByRequest(r *http.Request, initialPlaceholderID int) (setSyntax string, values []interface{}, err error)
 	SetDatabase(name string)
@@ -104,4 +186,7 @@ type Adapter interface {
 
 	ShowTable(schema, table string) (sc Scanner)
 	ShowTableCtx(ctx context.Context, schema, table string) (sc Scanner)
+	// ShowTableDetailCtx shows the columns, constraints, indexes and
+	// comments of a table as one document
+	ShowTableDetailCtx(ctx context.Context, schema, table string) (sc Scanner)
 }
@@ -0,0 +1,12 @@
+package adapters
+
+// Column describes a column of the result of a query
+type Column struct {
+	Name string
+	// Type is the name of the type on the database (e.g. INT4), the types
+	// of arrays are prefixed by an underscore (e.g. _INT4)
+	Type string
+	// Precision and Scale of numeric columns, zero when not declared
+	Precision int64
+	Scale     int64
+}
@@ -5,14 +5,17 @@ import (
 	"context"
 	"database/sql"
 	"database/sql/driver"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 	"testing"
 
 	"github.com/prest/prest/adapters"
 	"github.com/prest/prest/adapters/scanner"
 	"github.com/prest/prest/config"
+	"github.com/prest/prest/renderers"
 )
 
 // Item mock
@@ -185,6 +188,77 @@ func (m *Mock) QueryCtx(ctx context.Context, SQL string, params ...interface{})
 	return
 }
 
+// QueryStreamCtx mock
+func (m *Mock) QueryStreamCtx(ctx context.Context, SQL string, fn func(row []byte) error, params ...interface{}) (err error) {
+	m.t.Helper()
+	sc := m.perform(true)
+	if err = sc.Err(); err != nil {
+		return
+	}
+	var rows []json.RawMessage
+	if err = json.Unmarshal(sc.Bytes(), &rows); err != nil {
+		return
+	}
+	for _, row := range rows {
+		if err = fn(row); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// QueryRowsCtx mock
+func (m *Mock) QueryRowsCtx(ctx context.Context, SQL string, fn func(row []string) error, params ...interface{}) (err error) {
+	m.t.Helper()
+	sc := m.perform(true)
+	if err = sc.Err(); err != nil {
+		return
+	}
+	columns, rows, err := renderers.JSONRows(sc.Bytes())
+	if err != nil {
+		return
+	}
+	if err = fn(columns); err != nil {
+		return
+	}
+	for _, row := range rows {
+		if err = fn(row); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// QueryValuesCtx mock, the values are the text of the json values
+func (m *Mock) QueryValuesCtx(ctx context.Context, SQL string, columns func(cols []adapters.Column) error, row func(values []interface{}) error, params ...interface{}) (err error) {
+	m.t.Helper()
+	sc := m.perform(true)
+	if err = sc.Err(); err != nil {
+		return
+	}
+	names, rows, err := renderers.JSONRows(sc.Bytes())
+	if err != nil {
+		return
+	}
+	cols := make([]adapters.Column, len(names))
+	for i, name := range names {
+		cols[i] = adapters.Column{Name: name, Type: "TEXT"}
+	}
+	if err = columns(cols); err != nil {
+		return
+	}
+	for _, r := range rows {
+		values := make([]interface{}, len(r))
+		for i, v := range r {
+			values[i] = v
+		}
+		if err = row(values); err != nil {
+			return
+		}
+	}
+	return
+}
+
 // SchemaClause mock
 func (m *Mock) SchemaClause(req *http.Request) (query string, hasCount bool) {
 	m.t.Helper()
@@ -202,7 +276,7 @@ func (m *Mock) FieldsPermissions(r *http.Request, table string, op string) (fiel
 }
 
 // SelectFields mock
-func (m *Mock) SelectFields(fields []string) (sql string, err error) {
+func (m *Mock) SelectFields(fields []string, initialPlaceholderID int) (sql string, values []interface{}, err error) {
 	return
 }
 
@@ -216,8 +290,133 @@ func (m *Mock) JoinByRequest(r *http.Request) (values []string, err error) {
 	return
 }
 
+// BucketByRequest mock
+func (m *Mock) BucketByRequest(ctx context.Context, r *http.Request, initialPlaceholderID int) (field, bucketSQL string, values []interface{}, err error) {
+	return
+}
+
+// GapFillByRequest mock
+func (m *Mock) GapFillByRequest(r *http.Request, SQL string, initialPlaceholderID int) (gapFillSQL string, values []interface{}, err error) {
+	gapFillSQL = SQL
+	return
+}
+
+// TotalCountCtx mock
+func (m *Mock) TotalCountCtx(ctx context.Context, count string, SQL string, params ...interface{}) (total int64, err error) {
+	return
+}
+
+// TableCountEstimateCtx mock
+func (m *Mock) TableCountEstimateCtx(ctx context.Context, schema, table string) (total int64, err error) {
+	return
+}
+
+// TextSearchByRequest mock
+func (m *Mock) TextSearchByRequest(r *http.Request, initialPlaceholderID int) (rank string, headlines []string, values []interface{}, err error) {
+	return
+}
+
+// ExpandByRequest mock
+func (m *Mock) ExpandByRequest(ctx context.Context, r *http.Request, schema, table string) (expandSyntax []string, err error) {
+	return
+}
+
+// KeysetByRequest mock
+func (m *Mock) KeysetByRequest(r *http.Request, initialPlaceholderID int) (keysetSyntax string, values []interface{}, err error) {
+	return
+}
+
+// KeysetPage mock
+func (m *Mock) KeysetPage(r *http.Request, body []byte) (page []byte, next, prev string, err error) {
+	page = body
+	return
+}
+
+// PrimaryKeyCtx mock
+func (m *Mock) PrimaryKeyCtx(ctx context.Context, schema, table string) (columns []string, err error) {
+	columns = []string{"id"}
+	return
+}
+
+// PrimaryKeyWhereCtx mock
+func (m *Mock) PrimaryKeyWhereCtx(ctx context.Context, schema, table, pk string, initialPlaceholderID int) (whereSyntax string, values []interface{}, err error) {
+	whereSyntax = fmt.Sprintf(`"id" = $%d`, initialPlaceholderID)
+	values = []interface{}{pk}
+	return
+}
+
+// SoftDeleteWhere mock
+func (m *Mock) SoftDeleteWhere(table string) (whereSyntax string) {
+	return
+}
+
+// SoftDeleteSQL mock
+func (m *Mock) SoftDeleteSQL(database, schema, table string) (sql string) {
+	return
+}
+
+// WithDeletedByRequest mock
+func (m *Mock) WithDeletedByRequest(r *http.Request, table string) (withDeleted bool, err error) {
+	return
+}
+
+// IfMatchByRequest mock
+func (m *Mock) IfMatchByRequest(r *http.Request, table string, initialPlaceholderID int) (whereSyntax string, values []interface{}, err error) {
+	return
+}
+
+// FunctionArgsByRequest mock
+func (m *Mock) FunctionArgsByRequest(r *http.Request) (args map[string]interface{}, err error) {
+	args = make(map[string]interface{})
+	return
+}
+
+// FunctionPermissions mock
+func (m *Mock) FunctionPermissions(schema, function string) (access bool) {
+	m.t.Helper()
+	if schema == "information_schema" || strings.HasPrefix(schema, "pg_") {
+		return false
+	}
+	for _, t := range config.PrestConf.AccessConf.Tables {
+		if t.Name != function {
+			continue
+		}
+		for _, p := range t.Permissions {
+			if p == "execute" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CallFunctionCtx mock
+func (m *Mock) CallFunctionCtx(ctx context.Context, schema, name string, args map[string]interface{}, readOnly bool) (sc adapters.Scanner) {
+	m.t.Helper()
+	sc = m.perform(false)
+	return
+}
+
+// ParseBulkUpdateRequest mock
+func (m *Mock) ParseBulkUpdateRequest(r *http.Request) (rows []map[string]json.RawMessage, err error) {
+	err = json.NewDecoder(r.Body).Decode(&rows)
+	return
+}
+
+// BulkUpdateCtx mock
+func (m *Mock) BulkUpdateCtx(ctx context.Context, database, schema, table string, rows []map[string]json.RawMessage, where string, whereValues []interface{}, returning string) (sc adapters.Scanner) {
+	m.t.Helper()
+	sc = m.perform(false)
+	return
+}
+
+// OnConflictByRequest mock
+func (m *Mock) OnConflictByRequest(ctx context.Context, r *http.Request, schema, table, resolution, names string) (onConflictSyntax string, err error) {
+	return
+}
+
 // GroupByClause mock
-func (m *Mock) GroupByClause(r *http.Request) (groupBySQL string) {
+func (m *Mock) GroupByClause(r *http.Request, initialPlaceholderID int) (groupBySQL string, values []interface{}) {
 	return
 }
 
@@ -415,6 +614,13 @@ func (m *Mock) BatchInsertCopyCtx(ctx context.Context, dbname, schema, table str
 	return
 }
 
+// BatchUpsertCopyCtx mock
+func (m *Mock) BatchUpsertCopyCtx(ctx context.Context, dbname, schema, table string, keys []string, onConflict string, values ...interface{}) (sc adapters.Scanner) {
+	m.t.Helper()
+	sc = m.perform(false)
+	return
+}
+
 // ShowTable shows table structure
 func (m *Mock) ShowTable(schema, table string) (sc adapters.Scanner) {
 	return
@@ -425,6 +631,13 @@ func (m *Mock) ShowTableCtx(ctx context.Context, schema, table string) (sc adapt
 	return
 }
 
+// ShowTableDetailCtx mock
+func (m *Mock) ShowTableDetailCtx(ctx context.Context, schema, table string) (sc adapters.Scanner) {
+	m.t.Helper()
+	sc = m.perform(false)
+	return
+}
+
 // AddItem on mock object
 func (m *Mock) AddItem(body []byte, err error, isCount bool) {
 	i := Item{
@@ -2,6 +2,7 @@ package mock
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"errors"
 	"net/http"
@@ -432,6 +433,96 @@ func TestMock_Query(t *testing.T) {
 	}
 }
 
+func TestMock_QueryStreamCtx(t *testing.T) {
+	tests := []struct {
+		name    string
+		item    Item
+		rows    []string
+		wantErr bool
+	}{
+		{"stream rows", Item{Body: []byte(`[{"id":1},{"id":2}]`)}, []string{`{"id":1}`, `{"id":2}`}, false},
+		{"stream err", Item{Error: errors.New("test error")}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Mock{
+				mtx: &sync.RWMutex{},
+				t:   t,
+			}
+			m.AddItem(tt.item.Body, tt.item.Error, tt.item.IsCount)
+			var rows []string
+			err := m.QueryStreamCtx(context.Background(), "", func(row []byte) error {
+				rows = append(rows, string(row))
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Mock.QueryStreamCtx() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(rows, tt.rows) {
+				t.Errorf("Mock.QueryStreamCtx() rows = %v, want %v", rows, tt.rows)
+			}
+		})
+	}
+}
+
+func TestMock_QueryRowsCtx(t *testing.T) {
+	tests := []struct {
+		name    string
+		item    Item
+		rows    [][]string
+		wantErr bool
+	}{
+		{"rows", Item{Body: []byte(`[{"name":"a","id":1},{"name":null,"id":2}]`)}, [][]string{{"id", "name"}, {"1", "a"}, {"2", ""}}, false},
+		{"rows err", Item{Error: errors.New("test error")}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Mock{
+				mtx: &sync.RWMutex{},
+				t:   t,
+			}
+			m.AddItem(tt.item.Body, tt.item.Error, tt.item.IsCount)
+			var rows [][]string
+			err := m.QueryRowsCtx(context.Background(), "", func(row []string) error {
+				rows = append(rows, row)
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Mock.QueryRowsCtx() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(rows, tt.rows) {
+				t.Errorf("Mock.QueryRowsCtx() rows = %v, want %v", rows, tt.rows)
+			}
+		})
+	}
+}
+
+func TestMock_QueryValuesCtx(t *testing.T) {
+	m := &Mock{
+		mtx: &sync.RWMutex{},
+		t:   t,
+	}
+	m.AddItem([]byte(`[{"name":"a","id":1}]`), nil, false)
+	var columns []adapters.Column
+	var rows [][]interface{}
+	err := m.QueryValuesCtx(context.Background(), "", func(cols []adapters.Column) error {
+		columns = cols
+		return nil
+	}, func(values []interface{}) error {
+		rows = append(rows, values)
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Mock.QueryValuesCtx() error = %v", err)
+	}
+	if want := []adapters.Column{{Name: "id", Type: "TEXT"}, {Name: "name", Type: "TEXT"}}; !reflect.DeepEqual(columns, want) {
+		t.Errorf("Mock.QueryValuesCtx() columns = %v, want %v", columns, want)
+	}
+	if want := [][]interface{}{{"1", "a"}}; !reflect.DeepEqual(rows, want) {
+		t.Errorf("Mock.QueryValuesCtx() rows = %v, want %v", rows, want)
+	}
+}
+
 func TestMock_GetTransaction(t *testing.T) {
 	tests := []struct {
 		t       *testing.T
@@ -531,7 +622,7 @@ func TestMockEmptyMethods(t *testing.T) {
 	}
 
 	// SelectFields
-	_, err = mock.SelectFields(fields)
+	_, _, err = mock.SelectFields(fields, 1)
 	if err != nil {
 		t.Errorf("expected empty return, got: %s", err)
 	}
@@ -549,7 +640,7 @@ func TestMockEmptyMethods(t *testing.T) {
 	}
 
 	// GroupByClause
-	groupBySQL := mock.GroupByClause(&http.Request{})
+	groupBySQL, _ := mock.GroupByClause(&http.Request{}, 1)
 	if groupBySQL != "" {
 		t.Errorf("expected empty return, got: %s", groupBySQL)
 	}
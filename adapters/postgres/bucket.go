@@ -0,0 +1,153 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/prest/prest/adapters/postgres/statements"
+	"github.com/structy/log"
+)
+
+// bucketUnits maps the units of a bucket interval to the fields of
+// date_trunc and of the postgres intervals
+var bucketUnits = map[string]string{
+	"s":  "second",
+	"m":  "minute",
+	"h":  "hour",
+	"d":  "day",
+	"w":  "week",
+	"mo": "month",
+	"y":  "year",
+}
+
+// bucketIntervalRegex matches a bucket interval, e.g. 15m
+var bucketIntervalRegex = regexp.MustCompile(`^([1-9][0-9]*)(s|m|h|d|w|mo|y)$`)
+
+// timeBucket is a time bucket rule like `_bucket=created_at:1h`
+type timeBucket struct {
+	field string
+	count string
+	unit  string
+}
+
+// parseBucket parses the field and the interval of a time bucket rule
+func parseBucket(reqBucket string) (b timeBucket, err error) {
+	i := strings.LastIndex(reqBucket, ":")
+	if i < 0 {
+		err = errors.Wrapf(ErrInvalidBucket, "%s", reqBucket)
+		return
+	}
+	field, interval := reqBucket[:i], reqBucket[i+1:]
+	if chkInvalidIdentifier(field) {
+		err = errors.Wrapf(ErrInvalidIdentifier, "%s", field)
+		return
+	}
+	m := bucketIntervalRegex.FindStringSubmatch(interval)
+	if m == nil {
+		err = errors.Wrapf(ErrInvalidBucket, "%s", interval)
+		return
+	}
+	b = timeBucket{field: field, count: m[1], unit: bucketUnits[m[2]]}
+	return
+}
+
+// interval returns the size of the bucket as a postgres interval
+func (b timeBucket) interval() string {
+	return fmt.Sprintf("%s %s", b.count, b.unit)
+}
+
+// name returns the column of the bucket on the result
+func (b timeBucket) name() string {
+	return b.field[strings.LastIndex(b.field, ".")+1:]
+}
+
+// bucketSQL returns the expression that truncates the field to the start
+// of its bucket: time_bucket when timescaledb is installed, date_trunc for
+// a single unit and date_bin (postgres 14) for a multiple of seconds,
+// minutes, hours, days or weeks
+func (b timeBucket) bucketSQL(timescale bool, pid int) (expr string, values []interface{}, err error) {
+	field := quoteIdentifier(b.field)
+	switch {
+	case timescale:
+		expr = fmt.Sprintf("time_bucket($%d::interval, %s)", pid, field)
+		values = []interface{}{b.interval()}
+	case b.count == "1":
+		expr = fmt.Sprintf("date_trunc('%s', %s)", b.unit, field)
+	case b.unit == "month" || b.unit == "year":
+		err = errors.Wrapf(ErrInvalidBucket, "%s buckets require timescaledb", b.interval())
+	default:
+		// weeks start on monday as on date_trunc
+		expr = fmt.Sprintf("date_bin($%d::interval, %s, '2000-01-03')", pid, field)
+		values = []interface{}{b.interval()}
+	}
+	return
+}
+
+// BucketByRequest implements time bucketed aggregation
+//
+// `_bucket=field:interval` returns the name of the bucket column and the
+// expression of the bucket to be selected and grouped by, the interval is
+// a number followed by s, m, h, d, w, mo or y (e.g. 15m)
+func (adapter *Postgres) BucketByRequest(ctx context.Context, r *http.Request, initialPlaceholderID int) (field, bucketSQL string, values []interface{}, err error) {
+	reqBucket := r.URL.Query().Get("_bucket")
+	if reqBucket == "" {
+		return
+	}
+	b, err := parseBucket(reqBucket)
+	if err != nil {
+		return
+	}
+	timescale, err := adapter.hasTimescale(ctx)
+	if err != nil {
+		return
+	}
+	bucketSQL, values, err = b.bucketSQL(timescale, initialPlaceholderID)
+	if err != nil {
+		return "", "", nil, err
+	}
+	field = b.name()
+	return
+}
+
+// hasTimescale returns true when the timescaledb extension is installed on
+// the database of the request
+func (adapter *Postgres) hasTimescale(ctx context.Context) (installed bool, err error) {
+	db, err := getDBFromCtx(ctx)
+	if err != nil {
+		log.Errorln(err)
+		return
+	}
+	p, err := Prepare(db, statements.TimescaleInstalled)
+	if err != nil {
+		log.Errorln(err)
+		return
+	}
+	err = p.QueryRowContext(ctx).Scan(&installed)
+	return
+}
+
+// GapFillByRequest returns the empty buckets of a time bucketed query when
+// `_gapfill=true`, the query is returned as is otherwise
+func (adapter *Postgres) GapFillByRequest(r *http.Request, SQL string, initialPlaceholderID int) (gapFillSQL string, values []interface{}, err error) {
+	queries := r.URL.Query()
+	gapFillSQL = SQL
+	if queries.Get("_gapfill") != "true" {
+		return
+	}
+	reqBucket := queries.Get("_bucket")
+	if reqBucket == "" {
+		err = errors.Wrap(ErrInvalidBucket, "_gapfill requires _bucket")
+		return
+	}
+	b, err := parseBucket(reqBucket)
+	if err != nil {
+		return
+	}
+	gapFillSQL = fmt.Sprintf(statements.GapFill, SQL, quoteIdentifier(b.name()), initialPlaceholderID)
+	values = []interface{}{b.interval()}
+	return
+}
@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/prest/prest/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBucket(t *testing.T) {
+	var testCases = []struct {
+		description string
+		bucket      string
+		expected    timeBucket
+		err         error
+	}{
+		{"Hour", "created_at:1h", timeBucket{field: "created_at", count: "1", unit: "hour"}, nil},
+		{"Minutes of an aliased field", "r.time:15m", timeBucket{field: "r.time", count: "15", unit: "minute"}, nil},
+		{"Months", "created_at:3mo", timeBucket{field: "created_at", count: "3", unit: "month"}, nil},
+		{"Without interval", "created_at", timeBucket{}, ErrInvalidBucket},
+		{"Without count", "created_at:h", timeBucket{}, ErrInvalidBucket},
+		{"Zero count", "created_at:0h", timeBucket{}, ErrInvalidBucket},
+		{"Unknown unit", "created_at:1q", timeBucket{}, ErrInvalidBucket},
+		{"Invalid field", "0created_at:1h", timeBucket{}, ErrInvalidIdentifier},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			b, err := parseBucket(tc.bucket)
+			require.ErrorIs(t, err, tc.err)
+			require.Equal(t, tc.expected, b)
+		})
+	}
+}
+
+func TestBucketSQL(t *testing.T) {
+	var testCases = []struct {
+		description string
+		bucket      timeBucket
+		timescale   bool
+		expected    string
+		values      []interface{}
+		err         error
+	}{
+		{"time_bucket", timeBucket{field: "created_at", count: "15", unit: "minute"}, true, `time_bucket($2::interval, "created_at")`, []interface{}{"15 minute"}, nil},
+		{"date_trunc", timeBucket{field: "r.time", count: "1", unit: "day"}, false, `date_trunc('day', "r"."time")`, nil, nil},
+		{"date_bin", timeBucket{field: "created_at", count: "6", unit: "hour"}, false, `date_bin($2::interval, "created_at", '2000-01-03')`, []interface{}{"6 hour"}, nil},
+		{"Months without timescaledb", timeBucket{field: "created_at", count: "3", unit: "month"}, false, "", nil, ErrInvalidBucket},
+		{"Months with timescaledb", timeBucket{field: "created_at", count: "3", unit: "month"}, true, `time_bucket($2::interval, "created_at")`, []interface{}{"3 month"}, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			expr, values, err := tc.bucket.bucketSQL(tc.timescale, 2)
+			require.ErrorIs(t, err, tc.err)
+			require.Equal(t, tc.expected, expr)
+			require.Equal(t, tc.values, values)
+		})
+	}
+}
+
+func TestGapFillByRequest(t *testing.T) {
+	var testCases = []struct {
+		description string
+		url         string
+		expected    string
+		values      []interface{}
+		err         error
+	}{
+		{"Without gap-fill", "/prest-test/public/readings?_bucket=time:1h", "SELECT 1", nil, nil},
+		{
+			"Gap-fill",
+			"/prest-test/public/readings?_bucket=r.time:1h&_gapfill=true",
+			`WITH _bucket AS (SELECT 1) SELECT _bucket.*, _series AS "time" FROM generate_series((SELECT min("time") FROM _bucket), (SELECT max("time") FROM _bucket), $3::interval) _series LEFT JOIN _bucket ON _bucket."time" = _series ORDER BY _series`,
+			[]interface{}{"1 hour"},
+			nil,
+		},
+		{"Gap-fill without bucket", "/prest-test/public/readings?_gapfill=true", "SELECT 1", nil, ErrInvalidBucket},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodGet, tc.url, nil)
+			require.NoError(t, err)
+			gapFillSQL, values, err := config.PrestConf.Adapter.GapFillByRequest(r, "SELECT 1", 3)
+			require.ErrorIs(t, err, tc.err)
+			require.Equal(t, tc.expected, gapFillSQL)
+			require.Equal(t, tc.values, values)
+		})
+	}
+}
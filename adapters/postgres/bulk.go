@@ -0,0 +1,198 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/prest/prest/adapters"
+	"github.com/prest/prest/adapters/scanner"
+	"github.com/structy/log"
+)
+
+// bulkRow is the name of the column of the rows of a bulk update, their
+// columns are only reached through it so they do not clash with the
+// columns of the filters
+const bulkRow = `"_bulk"."_row"`
+
+// bulkGroup is the rows of a bulk update that set the same columns
+type bulkGroup struct {
+	columns []string
+	rows    []map[string]json.RawMessage
+}
+
+// ParseBulkUpdateRequest returns the rows of a bulk update, a json array
+// of objects holding the columns of the primary key and the ones to set
+func (adapter *Postgres) ParseBulkUpdateRequest(r *http.Request) (rows []map[string]json.RawMessage, err error) {
+	defer closer(r.Body)
+	if err = json.NewDecoder(r.Body).Decode(&rows); err != nil {
+		return
+	}
+	if len(rows) == 0 {
+		err = ErrBodyEmpty
+	}
+	return
+}
+
+// BulkUpdateCtx updates each row matched by its primary key with its own
+// values, in one transaction. The rows that set the same columns are
+// updated by one statement, the json of the rows is the first parameter
+// so the placeholders of where start at 2. Returns the total of rows
+// affected or the rows returned by all the statements
+func (adapter *Postgres) BulkUpdateCtx(ctx context.Context, database, schema, table string, rows []map[string]json.RawMessage, where string, whereValues []interface{}, returning string) (sc adapters.Scanner) {
+	key, err := adapter.PrimaryKeyCtx(ctx, schema, table)
+	if err != nil {
+		return &scanner.PrestScanner{Error: err}
+	}
+	groups, err := bulkGroups(rows, key)
+	if err != nil {
+		return &scanner.PrestScanner{Error: err}
+	}
+	tx, owned, err := beginTxCtx(ctx)
+	if err != nil {
+		log.Errorln(err)
+		return &scanner.PrestScanner{Error: err}
+	}
+	defer func() {
+		if !owned {
+			return
+		}
+		if err != nil {
+			if txerr := tx.Rollback(); txerr != nil {
+				log.Errorln(txerr)
+			}
+			return
+		}
+		if err = tx.Commit(); err != nil {
+			log.Errorln(err)
+			sc = &scanner.PrestScanner{Error: err}
+		}
+	}()
+
+	var rowsAffected int64
+	returned := make([]json.RawMessage, 0)
+	for _, group := range groups {
+		SQL := bulkUpdateSQL(database, schema, table, key, group.columns, where, returning)
+		var data []byte
+		if data, err = json.Marshal(group.rows); err != nil {
+			return &scanner.PrestScanner{Error: err}
+		}
+		params := append([]interface{}{string(data)}, whereValues...)
+		log.Debugln("generated SQL:", SQL, " parameters: ", params)
+		if returning == "" {
+			var n int64
+			result, execErr := tx.ExecContext(ctx, SQL, params...)
+			if err = execErr; err == nil {
+				n, err = result.RowsAffected()
+			}
+			if err != nil {
+				log.Errorln(err)
+				return &scanner.PrestScanner{Error: err}
+			}
+			rowsAffected += n
+			continue
+		}
+		var page []byte
+		if err = tx.QueryRowContext(ctx, SQL, params...).Scan(&page); err != nil {
+			log.Errorln(err)
+			return &scanner.PrestScanner{Error: err}
+		}
+		if len(page) == 0 {
+			continue
+		}
+		var pageRows []json.RawMessage
+		if err = json.Unmarshal(page, &pageRows); err != nil {
+			return &scanner.PrestScanner{Error: err}
+		}
+		returned = append(returned, pageRows...)
+	}
+
+	var body []byte
+	if returning == "" {
+		body, err = json.Marshal(map[string]interface{}{"rows_affected": rowsAffected})
+	} else {
+		body, err = json.Marshal(returned)
+	}
+	return &scanner.PrestScanner{
+		Error: err,
+		Buff:  bytes.NewBuffer(body),
+	}
+}
+
+// bulkGroups groups the rows of a bulk update by the columns they set, in
+// the order of their first row. Every row holds the columns of the key
+// and at least one other column
+func bulkGroups(rows []map[string]json.RawMessage, key []string) (groups []bulkGroup, err error) {
+	index := make(map[string]int)
+	for i, row := range rows {
+		var columns []string
+		for column := range row {
+			if chkInvalidIdentifier(column) {
+				err = errors.Wrapf(ErrInvalidIdentifier, "%s", column)
+				return nil, err
+			}
+			if !contains(key, column) {
+				columns = append(columns, column)
+			}
+		}
+		for _, column := range key {
+			if _, ok := row[column]; !ok {
+				err = errors.Wrapf(ErrInvalidBulkUpdate, "row %d has no %s", i, column)
+				return nil, err
+			}
+		}
+		if len(columns) == 0 {
+			err = errors.Wrapf(ErrInvalidBulkUpdate, "row %d sets no column", i)
+			return nil, err
+		}
+		sort.Strings(columns)
+		name := strings.Join(columns, ",")
+		g, ok := index[name]
+		if !ok {
+			g = len(groups)
+			index[name] = g
+			groups = append(groups, bulkGroup{columns: columns})
+		}
+		groups[g].rows = append(groups[g].rows, row)
+	}
+	return
+}
+
+// bulkUpdateSQL returns the update of the columns of the rows of the json
+// array of the first parameter, matched by the columns of the key
+func bulkUpdateSQL(database, schema, table string, key, columns []string, where, returning string) string {
+	set := make([]string, len(columns))
+	for i, column := range columns {
+		column = pq.QuoteIdentifier(column)
+		set[i] = fmt.Sprintf("%s = (%s).%s", column, bulkRow, column)
+	}
+	tableName := pq.QuoteIdentifier(table)
+	match := make([]string, len(key))
+	for i, column := range key {
+		column = pq.QuoteIdentifier(column)
+		match[i] = fmt.Sprintf("%s.%s = (%s).%s", tableName, column, bulkRow, column)
+	}
+	if where != "" {
+		match = append(match, fmt.Sprintf("(%s)", where))
+	}
+	SQL := fmt.Sprintf(
+		`UPDATE %s.%s.%s SET %s FROM (SELECT "_row" FROM jsonb_populate_recordset(NULL::%s.%s, $1::jsonb) AS "_row") AS "_bulk" WHERE %s`,
+		pq.QuoteIdentifier(database), pq.QuoteIdentifier(schema), tableName,
+		strings.Join(set, ", "),
+		pq.QuoteIdentifier(schema), tableName,
+		strings.Join(match, " AND "))
+	if returning == "" {
+		return SQL
+	}
+	// the columns of the rows of the update are not returned
+	if returning == "*" {
+		returning = tableName + ".*"
+	}
+	return fmt.Sprintf(`WITH "_updated" AS (%s RETURNING %s) SELECT jsonb_agg("_updated") FROM "_updated"`, SQL, returning)
+}
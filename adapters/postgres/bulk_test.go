@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkGroups(t *testing.T) {
+	rows := []map[string]json.RawMessage{
+		{"id": json.RawMessage(`1`), "name": json.RawMessage(`"a"`)},
+		{"id": json.RawMessage(`2`), "name": json.RawMessage(`"b"`), "age": json.RawMessage(`30`)},
+		{"id": json.RawMessage(`3`), "name": json.RawMessage(`"c"`)},
+	}
+	groups, err := bulkGroups(rows, []string{"id"})
+	require.NoError(t, err)
+	require.Equal(t, []bulkGroup{
+		{columns: []string{"name"}, rows: []map[string]json.RawMessage{rows[0], rows[2]}},
+		{columns: []string{"age", "name"}, rows: []map[string]json.RawMessage{rows[1]}},
+	}, groups)
+
+	var testCases = []struct {
+		description string
+		row         map[string]json.RawMessage
+		err         error
+	}{
+		{"Without key", map[string]json.RawMessage{"name": json.RawMessage(`"a"`)}, ErrInvalidBulkUpdate},
+		{"Only the key", map[string]json.RawMessage{"id": json.RawMessage(`1`)}, ErrInvalidBulkUpdate},
+		{"Invalid column", map[string]json.RawMessage{"id": json.RawMessage(`1`), "0name": json.RawMessage(`"a"`)}, ErrInvalidIdentifier},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			_, err := bulkGroups([]map[string]json.RawMessage{tc.row}, []string{"id"})
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestBulkUpdateSQL(t *testing.T) {
+	var testCases = []struct {
+		description string
+		key         []string
+		where       string
+		returning   string
+		expected    string
+	}{
+		{
+			"Rows affected",
+			[]string{"id"},
+			"",
+			"",
+			`UPDATE "prest"."public"."test" SET "name" = ("_bulk"."_row")."name" FROM (SELECT "_row" FROM jsonb_populate_recordset(NULL::"public"."test", $1::jsonb) AS "_row") AS "_bulk" WHERE "test"."id" = ("_bulk"."_row")."id"`,
+		},
+		{
+			"Composite key and filter",
+			[]string{"id", "year"},
+			`"age" > $2`,
+			"",
+			`UPDATE "prest"."public"."test" SET "name" = ("_bulk"."_row")."name" FROM (SELECT "_row" FROM jsonb_populate_recordset(NULL::"public"."test", $1::jsonb) AS "_row") AS "_bulk" WHERE "test"."id" = ("_bulk"."_row")."id" AND "test"."year" = ("_bulk"."_row")."year" AND ("age" > $2)`,
+		},
+		{
+			"Returning all columns",
+			[]string{"id"},
+			"",
+			"*",
+			`WITH "_updated" AS (UPDATE "prest"."public"."test" SET "name" = ("_bulk"."_row")."name" FROM (SELECT "_row" FROM jsonb_populate_recordset(NULL::"public"."test", $1::jsonb) AS "_row") AS "_bulk" WHERE "test"."id" = ("_bulk"."_row")."id" RETURNING "test".*) SELECT jsonb_agg("_updated") FROM "_updated"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			require.Equal(t, tc.expected, bulkUpdateSQL("prest", "public", "test", tc.key, []string{"name"}, tc.where, tc.returning))
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/prest/prest/adapters/postgres/statements"
+	"github.com/structy/log"
+)
+
+const (
+	// CountExact counts the rows of the query
+	CountExact = "exact"
+	// CountPlanned uses the planner estimate of the rows of the query
+	CountPlanned = "planned"
+)
+
+// TotalCountCtx returns the total of rows of a query, counted (exact) or
+// estimated by the planner (planned)
+func (adapter *Postgres) TotalCountCtx(ctx context.Context, count string, SQL string, params ...interface{}) (total int64, err error) {
+	switch count {
+	case CountExact:
+		SQL = fmt.Sprintf(statements.TotalCount, SQL)
+	case CountPlanned:
+		SQL = fmt.Sprintf(statements.PlannedCount, SQL)
+	default:
+		err = errors.Wrapf(ErrInvalidCount, "%s", count)
+		return
+	}
+	log.Debugln("generated SQL:", SQL, " parameters: ", params)
+	p, err := prepareCtx(ctx, SQL)
+	if err != nil {
+		log.Errorln(err)
+		return
+	}
+	if count == CountExact {
+		err = p.QueryRowContext(ctx, params...).Scan(&total)
+		return
+	}
+
+	var plan []byte
+	if err = p.QueryRowContext(ctx, params...).Scan(&plan); err != nil {
+		return
+	}
+	var explain []struct {
+		Plan struct {
+			Rows int64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err = json.Unmarshal(plan, &explain); err != nil {
+		return
+	}
+	if len(explain) == 0 {
+		err = errors.Wrap(ErrInvalidCount, "empty query plan")
+		return
+	}
+	total = explain[0].Plan.Rows
+	return
+}
+
+// TableCountEstimateCtx returns the number of rows of a table kept on the
+// statistics of the planner, -1 when there is none
+func (adapter *Postgres) TableCountEstimateCtx(ctx context.Context, schema, table string) (total int64, err error) {
+	db, err := getDBFromCtx(ctx)
+	if err != nil {
+		log.Errorln(err)
+		return
+	}
+	p, err := Prepare(db, statements.TableCountEstimate)
+	if err != nil {
+		log.Errorln(err)
+		return
+	}
+	err = p.QueryRowContext(ctx, schema, table).Scan(&total)
+	return
+}
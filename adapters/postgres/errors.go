@@ -6,10 +6,38 @@ var (
 	ErrJoinInvalidNumberOfArgs = errors.New("invalid number of arguments in join statement")
 	ErrInvalidIdentifier       = errors.New("invalid identifier")
 	ErrInvalidJoinClause       = errors.New("invalid join clause")
+	ErrJoinNotPermitted        = errors.New("you don't have permission to join")
 	ErrMustSelectOneField      = errors.New("you must select at least one field")
 	ErrNoTableName             = errors.New("unable to find table name")
 	ErrInvalidOperator         = errors.New("invalid operator")
 	ErrInvalidGroupFn          = errors.New("invalid group function")
+	ErrInvalidFilterGroup      = errors.New("invalid filter group")
+	ErrInvalidHaving           = errors.New("invalid having condition")
+	ErrInvalidBucket           = errors.New("invalid bucket")
+	ErrInvalidOrderFunction    = errors.New("invalid order function")
+	ErrInvalidCursor           = errors.New("invalid pagination cursor")
+	ErrKeysetWithoutOrder      = errors.New("keyset pagination requires _order")
+	ErrKeysetOrderExpression   = errors.New("keyset pagination requires _order columns without expressions or nulls placement")
+	ErrKeysetWithPage          = errors.New("keyset pagination can not be used with _page")
+	ErrKeysetBothDirections    = errors.New("use either _after or _before")
+	ErrInvalidCount            = errors.New("invalid count, use exact or planned")
+	ErrTextSearchNotFound      = errors.New("no full text search filter found")
+	ErrInvalidExpand           = errors.New("invalid expand")
+	ErrExpandNotFound          = errors.New("no foreign key found to expand")
+	ErrExpandAmbiguous         = errors.New("more than one foreign key found to expand")
+	ErrExpandNotPermitted      = errors.New("you don't have permission to expand")
+	ErrNoPrimaryKey            = errors.New("table has no primary key")
+	ErrInvalidPrimaryKey       = errors.New("invalid primary key")
+	ErrInvalidResolution       = errors.New("invalid resolution, use merge-duplicates or ignore-duplicates")
+	ErrInvalidOnConflict       = errors.New("invalid on conflict")
+	ErrInvalidBulkUpdate       = errors.New("invalid bulk update")
+	ErrWithDeletedNotPermitted = errors.New("you don't have permission to read deleted rows")
+	ErrTableNotFound           = errors.New("table not found")
+	ErrFunctionNotFound        = errors.New("function not found")
+	ErrFunctionAmbiguous       = errors.New("more than one overload of the function takes the arguments")
+	ErrFunctionVolatile        = errors.New("volatile functions can not be called with GET")
+	ErrFunctionReturns         = errors.New("functions returning record without out arguments or pseudo types can not be called")
+	ErrInvalidFunctionArgs     = errors.New("invalid function arguments, send a json object")
 	// ErrBodyEmpty err throw when body is empty
 	ErrBodyEmpty           = errors.New("body is empty")
 	ErrEmptyOrInvalidSlice = errors.New("empty or invalid slice")
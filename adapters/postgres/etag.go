@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// rowETagSQL returns the expression of the ETag of a row, the md5 of the
+// json object of the columns read from a table, as sent by a GET on the
+// route of the row
+func (adapter *Postgres) rowETagSQL(r *http.Request, table string) (expr string, err error) {
+	cols, err := adapter.FieldsPermissions(r, table, "read")
+	if err != nil {
+		return
+	}
+	if len(cols) == 0 {
+		err = ErrMustSelectOneField
+		return
+	}
+	if len(cols) == 1 && cols[0] == "*" {
+		return fmt.Sprintf("md5(to_jsonb(%s)::text)", pq.QuoteIdentifier(table)), nil
+	}
+	pairs := make([]string, 0, len(cols))
+	for _, col := range cols {
+		if chkInvalidIdentifier(col) || strings.ContainsAny(col, ".()*[]") {
+			err = errors.Wrapf(ErrInvalidIdentifier, "%s", col)
+			return
+		}
+		pairs = append(pairs, fmt.Sprintf("%s, %s.%s", pq.QuoteLiteral(col), pq.QuoteIdentifier(table), pq.QuoteIdentifier(col)))
+	}
+	return fmt.Sprintf("md5(jsonb_build_object(%s)::text)", strings.Join(pairs, ", ")), nil
+}
+
+// IfMatchByRequest returns the condition that matches the row only when
+// its ETag is one of the If-Match header, empty when the header is not
+// sent or is `*`. Weak ETags never match
+func (adapter *Postgres) IfMatchByRequest(r *http.Request, table string, initialPlaceholderID int) (whereSyntax string, values []interface{}, err error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if strings.HasPrefix(tag, "W/") || len(tag) < 2 || !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) {
+			continue
+		}
+		values = append(values, strings.Trim(tag, `"`))
+	}
+	if len(values) == 0 {
+		return "FALSE", nil, nil
+	}
+	expr, err := adapter.rowETagSQL(r, table)
+	if err != nil {
+		return "", nil, err
+	}
+	placeholders := make([]string, len(values))
+	for i := range values {
+		placeholders[i] = fmt.Sprintf("$%d", initialPlaceholderID+i)
+	}
+	whereSyntax = fmt.Sprintf("%s IN (%s)", expr, strings.Join(placeholders, ", "))
+	return
+}
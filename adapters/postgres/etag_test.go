@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prest/prest/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIfMatchByRequest(t *testing.T) {
+	restrict, tables := config.PrestConf.AccessConf.Restrict, config.PrestConf.AccessConf.Tables
+	defer func() {
+		config.PrestConf.AccessConf.Restrict, config.PrestConf.AccessConf.Tables = restrict, tables
+	}()
+	config.PrestConf.AccessConf.Tables = []config.TablesConf{
+		{Name: "test", Permissions: []string{"read", "write"}, Fields: []string{"id", "name"}},
+	}
+	adapter := &Postgres{}
+
+	var testCases = []struct {
+		description string
+		url         string
+		header      string
+		restrict    bool
+		where       string
+		values      []interface{}
+		err         error
+	}{
+		{"No If-Match", "/prest/public/test/1", "", false, "", nil, nil},
+		{"Any ETag", "/prest/public/test/1", "*", false, "", nil, nil},
+		{"One ETag", "/prest/public/test/1", `"abc"`, false, `md5(to_jsonb("test")::text) IN ($2)`, []interface{}{"abc"}, nil},
+		{"Several ETags", "/prest/public/test/1", `"abc", W/"def", "ghi"`, false, `md5(to_jsonb("test")::text) IN ($2, $3)`, []interface{}{"abc", "ghi"}, nil},
+		{"Weak ETags only", "/prest/public/test/1", `W/"abc"`, false, "FALSE", nil, nil},
+		{"Permitted fields", "/prest/public/test/1", `"abc"`, true, `md5(jsonb_build_object('id', "test"."id", 'name', "test"."name")::text) IN ($2)`, []interface{}{"abc"}, nil},
+		{"Selected fields", "/prest/public/test/1?_select=name", `"abc"`, false, `md5(jsonb_build_object('name', "test"."name")::text) IN ($2)`, []interface{}{"abc"}, nil},
+		{"Selected expression", "/prest/public/test/1?_select=count(*)", `"abc"`, false, "", nil, ErrInvalidIdentifier},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			config.PrestConf.AccessConf.Restrict = tc.restrict
+			r := httptest.NewRequest("PATCH", tc.url, nil)
+			if tc.header != "" {
+				r.Header.Set("If-Match", tc.header)
+			}
+			where, values, err := adapter.IfMatchByRequest(r, "test", 2)
+			require.ErrorIs(t, err, tc.err)
+			require.Equal(t, tc.where, where)
+			require.Equal(t, tc.values, values)
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/prest/prest/adapters/postgres/statements"
+)
+
+// expandAlias is the alias of the related table inside the subqueries
+const expandAlias = `"_expand"`
+
+// expandItemRegex matches a relation to expand, e.g. orders(id,total)
+var expandItemRegex = regexp.MustCompile(`^([^()]+?)(?:\((.*)\))?$`)
+
+// expandItem is a relation sent on the _expand query string
+type expandItem struct {
+	name    string
+	columns []string
+}
+
+// foreignKey is a foreign key constraint as listed by statements.ForeignKeys
+type foreignKey struct {
+	Name           string   `json:"name"`
+	Schema         string   `json:"schema"`
+	Table          string   `json:"table"`
+	Columns        []string `json:"columns"`
+	ForeignSchema  string   `json:"foreign_schema"`
+	ForeignTable   string   `json:"foreign_table"`
+	ForeignColumns []string `json:"foreign_columns"`
+}
+
+// ExpandByRequest implements embedded resources through foreign keys
+//
+// returns one select expression per relation sent on `_expand`, tables
+// referenced by the table are embedded as an object and tables that
+// reference it as an array
+func (adapter *Postgres) ExpandByRequest(ctx context.Context, r *http.Request, schema, table string) (expandSyntax []string, err error) {
+	reqExpand := r.URL.Query().Get("_expand")
+	if reqExpand == "" {
+		return
+	}
+	items, err := parseExpand(reqExpand)
+	if err != nil {
+		return
+	}
+	for i, item := range items {
+		if !adapter.TablePermissions(item.name, "read") {
+			err = errors.Wrapf(ErrExpandNotPermitted, "%s", item.name)
+			return
+		}
+		items[i].columns = permittedFields(item.name, "read", item.columns)
+		if len(items[i].columns) == 0 {
+			err = errors.Wrapf(ErrExpandNotPermitted, "%s", item.name)
+			return
+		}
+	}
+
+	sc := adapter.QueryCtx(ctx, statements.ForeignKeys, schema, table)
+	if err = sc.Err(); err != nil {
+		return
+	}
+	var fks []foreignKey
+	if err = json.Unmarshal(sc.Bytes(), &fks); err != nil {
+		return
+	}
+	for _, item := range items {
+		var expr string
+		notDeleted := adapter.relatedSoftDeleteWhere(r, expandAlias, item.name)
+		expr, err = expandSQL(schema, table, item, fks, notDeleted)
+		if err != nil {
+			return nil, err
+		}
+		expandSyntax = append(expandSyntax, expr)
+	}
+	return
+}
+
+// parseExpand parses a list of relations like `orders(id,total),customer`,
+// a relation without columns expands all of them
+func parseExpand(reqExpand string) (items []expandItem, err error) {
+	list, err := splitFilterList(reqExpand)
+	if err != nil {
+		err = errors.Wrapf(ErrInvalidExpand, "%s", reqExpand)
+		return
+	}
+	for _, item := range list {
+		m := expandItemRegex.FindStringSubmatch(strings.TrimSpace(item))
+		if m == nil {
+			err = errors.Wrapf(ErrInvalidExpand, "%s", item)
+			return nil, err
+		}
+		name := strings.TrimSpace(m[1])
+		if chkInvalidIdentifier(name) || strings.ContainsAny(name, ".*[]") {
+			err = errors.Wrapf(ErrInvalidIdentifier, "%s", name)
+			return nil, err
+		}
+		var columns []string
+		if strings.TrimSpace(m[2]) != "" {
+			for _, col := range strings.Split(m[2], ",") {
+				col = strings.TrimSpace(col)
+				if col != "*" && (chkInvalidIdentifier(col) || strings.ContainsAny(col, ".()*[]")) {
+					err = errors.Wrapf(ErrInvalidIdentifier, "%s", col)
+					return nil, err
+				}
+				columns = append(columns, col)
+			}
+		}
+		items = append(items, expandItem{name: name, columns: columns})
+	}
+	return
+}
+
+// expandSQL builds the subquery that embeds the rows of a relation, the
+// foreign key is looked up by the name of the related table. notDeleted
+// excludes the soft deleted rows of the relation when not empty
+func expandSQL(schema, table string, item expandItem, fks []foreignKey, notDeleted string) (expr string, err error) {
+	var matches []foreignKey
+	var toOne []bool
+	for _, fk := range fks {
+		if fk.Schema == schema && fk.Table == table && fk.ForeignTable == item.name {
+			matches = append(matches, fk)
+			toOne = append(toOne, true)
+		}
+		if fk.ForeignSchema == schema && fk.ForeignTable == table && fk.Table == item.name {
+			matches = append(matches, fk)
+			toOne = append(toOne, false)
+		}
+	}
+	switch {
+	case len(matches) == 0:
+		err = errors.Wrapf(ErrExpandNotFound, "%s", item.name)
+		return
+	case len(matches) > 1:
+		err = errors.Wrapf(ErrExpandAmbiguous, "%s", item.name)
+		return
+	}
+	fk := matches[0]
+
+	parent := quoteIdentifier(schema + "." + table)
+	related := quoteIdentifier(fk.Schema + "." + fk.Table)
+	relatedColumns, parentColumns := fk.Columns, fk.ForeignColumns
+	if toOne[0] {
+		related = quoteIdentifier(fk.ForeignSchema + "." + fk.ForeignTable)
+		relatedColumns, parentColumns = fk.ForeignColumns, fk.Columns
+	}
+	conditions := make([]string, 0, len(relatedColumns))
+	for i := range relatedColumns {
+		conditions = append(conditions, fmt.Sprintf(`%s.%s = %s.%s`,
+			expandAlias, quoteIdentifier(relatedColumns[i]), parent, quoteIdentifier(parentColumns[i])))
+	}
+	if notDeleted != "" {
+		conditions = append(conditions, notDeleted)
+	}
+	columns := make([]string, 0, len(item.columns))
+	for _, col := range item.columns {
+		if col == "*" {
+			columns = append(columns, expandAlias+".*")
+			continue
+		}
+		columns = append(columns, fmt.Sprintf(`%s.%s`, expandAlias, quoteIdentifier(col)))
+	}
+	subquery := fmt.Sprintf(`SELECT %s FROM %s AS %s WHERE %s`,
+		strings.Join(columns, ", "), related, expandAlias, strings.Join(conditions, " AND "))
+	if toOne[0] {
+		expr = fmt.Sprintf(`(SELECT to_jsonb(_row) FROM (%s LIMIT 1) _row) AS "%s"`, subquery, item.name)
+		return
+	}
+	expr = fmt.Sprintf(`(SELECT COALESCE(jsonb_agg(_row), '[]'::jsonb) FROM (%s) _row) AS "%s"`, subquery, item.name)
+	return
+}
@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExpand(t *testing.T) {
+	var testCases = []struct {
+		description string
+		expand      string
+		items       []expandItem
+		err         error
+	}{
+		{
+			"Relations with and without columns",
+			"orders(id,total),customer",
+			[]expandItem{{name: "orders", columns: []string{"id", "total"}}, {name: "customer"}},
+			nil,
+		},
+		{"Empty column list", "orders()", []expandItem{{name: "orders"}}, nil},
+		{"All columns", "orders(*)", []expandItem{{name: "orders", columns: []string{"*"}}}, nil},
+		{"Unbalanced", "orders(id,total", nil, ErrInvalidExpand},
+		{"Empty relation", "orders,,customer", nil, ErrInvalidExpand},
+		{"Invalid relation", "public.orders", nil, ErrInvalidIdentifier},
+		{"Invalid column", "orders(0id)", nil, ErrInvalidIdentifier},
+		{"Nested relation", "orders(id,items(id))", nil, ErrInvalidIdentifier},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			items, err := parseExpand(tc.expand)
+			require.ErrorIs(t, err, tc.err)
+			require.Equal(t, tc.items, items)
+		})
+	}
+}
+
+func TestExpandSQL(t *testing.T) {
+	fks := []foreignKey{
+		{
+			Name:           "orders_customer_id_fkey",
+			Schema:         "public",
+			Table:          "orders",
+			Columns:        []string{"customer_id"},
+			ForeignSchema:  "public",
+			ForeignTable:   "customer",
+			ForeignColumns: []string{"id"},
+		},
+		{
+			Name:           "shipments_order_fkey",
+			Schema:         "public",
+			Table:          "shipments",
+			Columns:        []string{"order_id", "order_year"},
+			ForeignSchema:  "public",
+			ForeignTable:   "orders",
+			ForeignColumns: []string{"id", "year"},
+		},
+		{
+			Name:           "orders_seller_id_fkey",
+			Schema:         "public",
+			Table:          "orders",
+			Columns:        []string{"seller_id"},
+			ForeignSchema:  "public",
+			ForeignTable:   "users",
+			ForeignColumns: []string{"id"},
+		},
+		{
+			Name:           "orders_buyer_id_fkey",
+			Schema:         "public",
+			Table:          "orders",
+			Columns:        []string{"buyer_id"},
+			ForeignSchema:  "public",
+			ForeignTable:   "users",
+			ForeignColumns: []string{"id"},
+		},
+	}
+	var testCases = []struct {
+		description string
+		table       string
+		item        expandItem
+		expected    string
+		err         error
+	}{
+		{
+			"Many to one",
+			"orders",
+			expandItem{name: "customer", columns: []string{"name"}},
+			`(SELECT to_jsonb(_row) FROM (SELECT "_expand"."name" FROM "public"."customer" AS "_expand" WHERE "_expand"."id" = "public"."orders"."customer_id" LIMIT 1) _row) AS "customer"`,
+			nil,
+		},
+		{
+			"One to many",
+			"customer",
+			expandItem{name: "orders", columns: []string{"id", "total"}},
+			`(SELECT COALESCE(jsonb_agg(_row), '[]'::jsonb) FROM (SELECT "_expand"."id", "_expand"."total" FROM "public"."orders" AS "_expand" WHERE "_expand"."customer_id" = "public"."customer"."id") _row) AS "orders"`,
+			nil,
+		},
+		{
+			"Composite key",
+			"orders",
+			expandItem{name: "shipments", columns: []string{"*"}},
+			`(SELECT COALESCE(jsonb_agg(_row), '[]'::jsonb) FROM (SELECT "_expand".* FROM "public"."shipments" AS "_expand" WHERE "_expand"."order_id" = "public"."orders"."id" AND "_expand"."order_year" = "public"."orders"."year") _row) AS "shipments"`,
+			nil,
+		},
+		{"Not related", "customer", expandItem{name: "shipments", columns: []string{"*"}}, "", ErrExpandNotFound},
+		{"Ambiguous", "orders", expandItem{name: "users", columns: []string{"*"}}, "", ErrExpandAmbiguous},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			expr, err := expandSQL("public", tc.table, tc.item, fks, "")
+			require.ErrorIs(t, err, tc.err)
+			require.Equal(t, tc.expected, expr)
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package postgres
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// filterGroupKeys maps the query string keys that open a filter group to
+// the boolean operator used to join its filters
+var filterGroupKeys = map[string]string{
+	"_and": "and",
+	"_or":  "or",
+	"_not": "not",
+}
+
+var (
+	// filterGroupRegex matches a nested group, e.g. or(a.$eq.1,b.$eq.2)
+	filterGroupRegex = regexp.MustCompile(`^(?i)(and|or|not)\((.*)\)$`)
+	// filterConditionRegex matches a single filter, e.g. c.name.$eq.prest
+	filterConditionRegex = regexp.MustCompile(`^(.+?)\.(\$[a-z]+)(?:\.(.*))?$`)
+)
+
+// conditionFunc builds the SQL of a single condition of a group
+type conditionFunc func(item string, pid int) (expr string, values []interface{}, err error)
+
+// filterGroup builds the SQL of a filter group like
+// `(status.$eq.open,and(priority.$gte.3,owner.$null))`, the placeholders
+// are numbered starting at pid and each condition is built by condition
+func filterGroup(op, group string, pid int, condition conditionFunc) (groupSQL string, values []interface{}, err error) {
+	group = strings.TrimSpace(group)
+	if len(group) < 2 || group[0] != '(' || group[len(group)-1] != ')' {
+		err = errors.Wrapf(ErrInvalidFilterGroup, "%s", group)
+		return
+	}
+	items, err := splitFilterList(group[1 : len(group)-1])
+	if err != nil {
+		return
+	}
+	exprs := make([]string, 0, len(items))
+	for _, item := range items {
+		var expr string
+		var exprValues []interface{}
+		if m := filterGroupRegex.FindStringSubmatch(item); m != nil {
+			expr, exprValues, err = filterGroup(strings.ToLower(m[1]), "("+m[2]+")", pid, condition)
+		} else {
+			expr, exprValues, err = condition(item, pid)
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		exprs = append(exprs, expr)
+		values = append(values, exprValues...)
+		pid += len(exprValues)
+	}
+	switch op {
+	case "and":
+		groupSQL = "(" + strings.Join(exprs, " AND ") + ")"
+	case "or":
+		groupSQL = "(" + strings.Join(exprs, " OR ") + ")"
+	case "not":
+		groupSQL = "NOT (" + strings.Join(exprs, " AND ") + ")"
+	default:
+		err = errors.Wrapf(ErrInvalidFilterGroup, "%s", op)
+		return "", nil, err
+	}
+	return
+}
+
+// filterCondition builds the SQL of a single `field.$op.value` filter
+func filterCondition(item string, pid int) (expr string, values []interface{}, err error) {
+	m := filterConditionRegex.FindStringSubmatch(strings.TrimSpace(item))
+	if m == nil {
+		err = errors.Wrapf(ErrInvalidFilterGroup, "%s", item)
+		return
+	}
+	if chkInvalidIdentifier(m[1]) {
+		err = errors.Wrapf(ErrInvalidIdentifier, "%s", m[1])
+		return
+	}
+	return whereExpression(quoteIdentifier(m[1]), m[2], unquoteFilterValue(m[3]), pid)
+}
+
+// unquoteFilterValue removes the double quotes or parentheses used to
+// protect commas and parentheses inside a filter value
+func unquoteFilterValue(value string) string {
+	if len(value) < 2 {
+		return value
+	}
+	first, last := value[0], value[len(value)-1]
+	switch {
+	case first == '"' && last == '"':
+		value = value[1 : len(value)-1]
+		value = strings.Replace(value, `\"`, `"`, -1)
+		value = strings.Replace(value, `\\`, `\`, -1)
+	case first == '(' && last == ')':
+		value = value[1 : len(value)-1]
+	}
+	return value
+}
+
+// splitFilterList splits a filter list by the commas that are not
+// enclosed by parentheses or double quotes
+func splitFilterList(list string) (items []string, err error) {
+	depth, quoted, start := 0, false, 0
+	for i := 0; i < len(list); i++ {
+		switch list[i] {
+		case '\\':
+			if quoted {
+				i++
+			}
+		case '"':
+			quoted = !quoted
+		case '(':
+			if !quoted {
+				depth++
+			}
+		case ')':
+			if !quoted {
+				depth--
+			}
+		case ',':
+			if !quoted && depth == 0 {
+				items = append(items, list[start:i])
+				start = i + 1
+			}
+		}
+		if depth < 0 {
+			break
+		}
+	}
+	if depth != 0 || quoted {
+		err = errors.Wrapf(ErrInvalidFilterGroup, "unbalanced %s", list)
+		return nil, err
+	}
+	items = append(items, list[start:])
+	for _, item := range items {
+		if strings.TrimSpace(item) == "" {
+			err = errors.Wrapf(ErrInvalidFilterGroup, "empty filter in %s", list)
+			return nil, err
+		}
+	}
+	return
+}
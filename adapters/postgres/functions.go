@@ -0,0 +1,223 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/prest/prest/adapters"
+	"github.com/prest/prest/adapters/postgres/statements"
+	"github.com/prest/prest/adapters/scanner"
+	"github.com/prest/prest/config"
+	permissions "github.com/prest/prest/middlewares/statements"
+	"github.com/structy/log"
+)
+
+// function is an overload of a stored function read from pg_proc
+type function struct {
+	Volatility string `json:"volatility"`
+	Set        bool   `json:"set"`
+	Defaults   int    `json:"defaults"`
+	Returns    string `json:"returns"`
+	Args       []struct {
+		Name     *string `json:"name"`
+		Type     string  `json:"type"`
+		Variadic bool    `json:"variadic"`
+	} `json:"args"`
+}
+
+// FunctionArgsByRequest returns the named arguments of a function call,
+// the json object of the body of a POST or the query string of a GET but
+// its parameters starting with `_`
+func (adapter *Postgres) FunctionArgsByRequest(r *http.Request) (args map[string]interface{}, err error) {
+	args = make(map[string]interface{})
+	if r.Method == http.MethodGet {
+		for name, values := range r.URL.Query() {
+			// the parameters of prestd, as the format of the response
+			if strings.HasPrefix(name, "_") {
+				continue
+			}
+			args[name] = values[len(values)-1]
+		}
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return
+	}
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.UseNumber()
+	if err = d.Decode(&args); err != nil {
+		err = errors.Wrap(ErrInvalidFunctionArgs, err.Error())
+	}
+	return
+}
+
+// FunctionPermissions returns true when the function is listed in the
+// access tables with the execute permission, restricted access or not.
+// The functions of the system schemas are never called
+func (adapter *Postgres) FunctionPermissions(schema, function string) (access bool) {
+	if schema == "information_schema" || strings.HasPrefix(schema, "pg_") {
+		return false
+	}
+	for _, t := range config.PrestConf.AccessConf.Tables {
+		if t.Name != function {
+			continue
+		}
+		for _, p := range t.Permissions {
+			if p == permissions.EXECUTE {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CallFunctionCtx calls the overload of a function taking the named
+// arguments and returns its result as json: the value of scalar functions,
+// an object for composite ones and an array of them for set-returning
+// ones. Volatile functions are not called when readOnly is true
+func (adapter *Postgres) CallFunctionCtx(ctx context.Context, schema, name string, args map[string]interface{}, readOnly bool) (sc adapters.Scanner) {
+	sc = adapter.QueryCtx(ctx, statements.Function, schema, name)
+	if err := sc.Err(); err != nil {
+		return
+	}
+	var overloads []function
+	if err := json.Unmarshal(sc.Bytes(), &overloads); err != nil {
+		return &scanner.PrestScanner{Error: err}
+	}
+	fn, err := resolveFunction(overloads, args)
+	if err != nil {
+		return &scanner.PrestScanner{Error: errors.Wrapf(err, "%s.%s", schema, name)}
+	}
+	if readOnly && fn.Volatility == "v" {
+		return &scanner.PrestScanner{Error: errors.Wrapf(ErrFunctionVolatile, "%s.%s", schema, name)}
+	}
+	SQL, values, err := functionSQL(schema, name, fn, args)
+	if err != nil {
+		return &scanner.PrestScanner{Error: err}
+	}
+
+	log.Debugln("generated SQL:", SQL, " parameters: ", values)
+	p, err := prepareCtx(ctx, SQL)
+	if err != nil {
+		log.Errorln(err)
+		return &scanner.PrestScanner{Error: err}
+	}
+	var jsonData []byte
+	err = p.QueryRowContext(ctx, values...).Scan(&jsonData)
+	if len(jsonData) == 0 {
+		jsonData = []byte("null")
+	}
+	return &scanner.PrestScanner{
+		Error: err,
+		Buff:  bytes.NewBuffer(jsonData),
+	}
+}
+
+// resolveFunction returns the overload whose arguments are the ones sent,
+// the arguments with defaults may be left out
+func resolveFunction(overloads []function, args map[string]interface{}) (fn function, err error) {
+	if len(overloads) == 0 {
+		err = ErrFunctionNotFound
+		return
+	}
+	var matches []function
+	for _, o := range overloads {
+		required := len(o.Args) - o.Defaults
+		sent := 0
+		ok := true
+		for i, arg := range o.Args {
+			if arg.Name == nil {
+				if i < required {
+					ok = false
+				}
+				continue
+			}
+			if _, has := args[*arg.Name]; has {
+				sent++
+			} else if i < required {
+				ok = false
+			}
+		}
+		if ok && sent == len(args) {
+			matches = append(matches, o)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		names := make([]string, 0, len(args))
+		for name := range args {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		err = errors.Wrapf(ErrFunctionNotFound, "no overload takes the arguments (%s)", strings.Join(names, ", "))
+	case 1:
+		fn = matches[0]
+	default:
+		err = ErrFunctionAmbiguous
+	}
+	return
+}
+
+// functionSQL returns the call of a function with its arguments bound in
+// named notation, json arrays sent to array arguments are converted
+func functionSQL(schema, name string, fn function, args map[string]interface{}) (SQL string, values []interface{}, err error) {
+	var params []string
+	for _, arg := range fn.Args {
+		if arg.Name == nil {
+			continue
+		}
+		v, ok := args[*arg.Name]
+		if !ok {
+			continue
+		}
+		placeholder := fmt.Sprintf("$%d", len(values)+1)
+		expr := fmt.Sprintf("%s::%s", placeholder, arg.Type)
+		switch value := v.(type) {
+		case []interface{}, map[string]interface{}:
+			var byt []byte
+			if byt, err = json.Marshal(value); err != nil {
+				return
+			}
+			v = string(byt)
+			if _, isArray := value.([]interface{}); isArray && strings.HasSuffix(arg.Type, "[]") {
+				expr = fmt.Sprintf("ARRAY(SELECT jsonb_array_elements_text(%s::jsonb))::%s", placeholder, arg.Type)
+			}
+		case json.Number:
+			v = value.String()
+		}
+		if arg.Variadic {
+			expr = "VARIADIC " + expr
+		}
+		params = append(params, fmt.Sprintf("%s => %s", pq.QuoteIdentifier(*arg.Name), expr))
+		values = append(values, v)
+	}
+	call := fmt.Sprintf("%s.%s(%s)", pq.QuoteIdentifier(schema), pq.QuoteIdentifier(name), strings.Join(params, ", "))
+
+	switch {
+	case fn.Returns == "pseudo":
+		err = ErrFunctionReturns
+	case fn.Returns == "void":
+		SQL = fmt.Sprintf("SELECT 'null'::jsonb FROM %s s", call)
+	case fn.Returns == "composite" && fn.Set:
+		SQL = fmt.Sprintf("SELECT COALESCE(jsonb_agg(to_jsonb(s.*)), '[]') FROM %s s", call)
+	case fn.Returns == "composite":
+		SQL = fmt.Sprintf("SELECT to_jsonb(s.*) FROM %s s", call)
+	case fn.Set:
+		SQL = fmt.Sprintf("SELECT COALESCE(jsonb_agg(s.v), '[]') FROM %s AS s(v)", call)
+	default:
+		SQL = fmt.Sprintf("SELECT to_jsonb(s.v) FROM %s AS s(v)", call)
+	}
+	return
+}
@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prest/prest/config"
+	"github.com/stretchr/testify/require"
+)
+
+func testFunction(t *testing.T, def string) (fn function) {
+	require.NoError(t, json.Unmarshal([]byte(def), &fn))
+	return
+}
+
+func TestResolveFunction(t *testing.T) {
+	add := `{"volatility": "i", "returns": "scalar", "defaults": 1, "args": [{"name": "a", "type": "integer"}, {"name": "b", "type": "integer"}]}`
+	concat := `{"volatility": "i", "returns": "scalar", "defaults": 1, "args": [{"name": "a", "type": "text"}, {"name": "c", "type": "text"}]}`
+	unnamed := `{"volatility": "i", "returns": "scalar", "args": [{"name": null, "type": "integer"}]}`
+
+	var testCases = []struct {
+		description string
+		overloads   []string
+		args        map[string]interface{}
+		resolved    int
+		err         error
+	}{
+		{"All arguments", []string{add}, map[string]interface{}{"a": 1, "b": 2}, 0, nil},
+		{"Argument with default left out", []string{add}, map[string]interface{}{"a": 1}, 0, nil},
+		{"Required argument left out", []string{add}, map[string]interface{}{"b": 2}, 0, ErrFunctionNotFound},
+		{"Unknown argument", []string{add}, map[string]interface{}{"a": 1, "z": 2}, 0, ErrFunctionNotFound},
+		{"Overload by argument names", []string{add, concat}, map[string]interface{}{"a": "x", "c": "y"}, 1, nil},
+		{"Ambiguous overloads", []string{add, concat}, map[string]interface{}{"a": 1}, 0, ErrFunctionAmbiguous},
+		{"Unnamed argument", []string{unnamed}, map[string]interface{}{}, 0, ErrFunctionNotFound},
+		{"No function", nil, map[string]interface{}{}, 0, ErrFunctionNotFound},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			var overloads []function
+			for _, def := range tc.overloads {
+				overloads = append(overloads, testFunction(t, def))
+			}
+			fn, err := resolveFunction(overloads, tc.args)
+			require.ErrorIs(t, err, tc.err)
+			if tc.err == nil {
+				require.Equal(t, overloads[tc.resolved], fn)
+			}
+		})
+	}
+}
+
+func TestFunctionSQL(t *testing.T) {
+	var testCases = []struct {
+		description string
+		function    string
+		args        string
+		sql         string
+		values      []interface{}
+		err         error
+	}{
+		{"Scalar", `{"returns": "scalar", "args": [{"name": "a", "type": "integer"}, {"name": "b", "type": "integer"}]}`, `{"a": 1, "b": 2}`,
+			`SELECT to_jsonb(s.v) FROM "public"."add"("a" => $1::integer, "b" => $2::integer) AS s(v)`, []interface{}{"1", "2"}, nil},
+		{"Argument with default left out", `{"returns": "scalar", "defaults": 1, "args": [{"name": "a", "type": "integer"}, {"name": "b", "type": "integer"}]}`, `{"a": 1}`,
+			`SELECT to_jsonb(s.v) FROM "public"."add"("a" => $1::integer) AS s(v)`, []interface{}{"1"}, nil},
+		{"Set of scalars", `{"returns": "scalar", "set": true, "args": [{"name": "n", "type": "integer"}]}`, `{"n": 3}`,
+			`SELECT COALESCE(jsonb_agg(s.v), '[]') FROM "public"."add"("n" => $1::integer) AS s(v)`, []interface{}{"3"}, nil},
+		{"Composite", `{"returns": "composite", "args": [{"name": "id", "type": "integer"}]}`, `{"id": "5"}`,
+			`SELECT to_jsonb(s.*) FROM "public"."add"("id" => $1::integer) s`, []interface{}{"5"}, nil},
+		{"Set of composites", `{"returns": "composite", "set": true, "args": []}`, `{}`,
+			`SELECT COALESCE(jsonb_agg(to_jsonb(s.*)), '[]') FROM "public"."add"() s`, nil, nil},
+		{"Void", `{"returns": "void", "args": [{"name": "flag", "type": "boolean"}]}`, `{"flag": true}`,
+			`SELECT 'null'::jsonb FROM "public"."add"("flag" => $1::boolean) s`, []interface{}{true}, nil},
+		{"Json argument", `{"returns": "scalar", "args": [{"name": "doc", "type": "jsonb"}]}`, `{"doc": {"a": [1]}}`,
+			`SELECT to_jsonb(s.v) FROM "public"."add"("doc" => $1::jsonb) AS s(v)`, []interface{}{`{"a":[1]}`}, nil},
+		{"Array argument", `{"returns": "scalar", "args": [{"name": "ids", "type": "integer[]"}]}`, `{"ids": [1, 2]}`,
+			`SELECT to_jsonb(s.v) FROM "public"."add"("ids" => ARRAY(SELECT jsonb_array_elements_text($1::jsonb))::integer[]) AS s(v)`, []interface{}{"[1,2]"}, nil},
+		{"Variadic argument", `{"returns": "scalar", "args": [{"name": "ids", "type": "integer[]", "variadic": true}]}`, `{"ids": "{1,2}"}`,
+			`SELECT to_jsonb(s.v) FROM "public"."add"("ids" => VARIADIC $1::integer[]) AS s(v)`, []interface{}{"{1,2}"}, nil},
+		{"Null argument", `{"returns": "scalar", "args": [{"name": "a", "type": "text"}]}`, `{"a": null}`,
+			`SELECT to_jsonb(s.v) FROM "public"."add"("a" => $1::text) AS s(v)`, []interface{}{nil}, nil},
+		{"Pseudo type", `{"returns": "pseudo", "args": []}`, `{}`, "", nil, ErrFunctionReturns},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/_RPC/prest/public/add", strings.NewReader(tc.args))
+			args, err := (&Postgres{}).FunctionArgsByRequest(r)
+			require.NoError(t, err)
+			sql, values, err := functionSQL("public", "add", testFunction(t, tc.function), args)
+			require.ErrorIs(t, err, tc.err)
+			require.Equal(t, tc.sql, sql)
+			require.Equal(t, tc.values, values)
+		})
+	}
+}
+
+func TestFunctionArgsByRequest(t *testing.T) {
+	adapter := &Postgres{}
+
+	r := httptest.NewRequest("GET", "/_RPC/prest/public/add?a=1&b=2&_renderer=xml&_select=a", nil)
+	args, err := adapter.FunctionArgsByRequest(r)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"a": "1", "b": "2"}, args)
+
+	r = httptest.NewRequest("POST", "/_RPC/prest/public/add", nil)
+	args, err = adapter.FunctionArgsByRequest(r)
+	require.NoError(t, err)
+	require.Empty(t, args)
+
+	r = httptest.NewRequest("POST", "/_RPC/prest/public/add", strings.NewReader(`[1, 2]`))
+	_, err = adapter.FunctionArgsByRequest(r)
+	require.ErrorIs(t, err, ErrInvalidFunctionArgs)
+}
+
+func TestFunctionPermissions(t *testing.T) {
+	restrict, tables := config.PrestConf.AccessConf.Restrict, config.PrestConf.AccessConf.Tables
+	defer func() {
+		config.PrestConf.AccessConf.Restrict, config.PrestConf.AccessConf.Tables = restrict, tables
+	}()
+	config.PrestConf.AccessConf.Restrict = false
+	config.PrestConf.AccessConf.Tables = []config.TablesConf{
+		{Name: "add", Permissions: []string{"execute"}},
+		{Name: "test", Permissions: []string{"read", "write"}},
+		{Name: "pg_sleep", Permissions: []string{"execute"}},
+	}
+	adapter := &Postgres{}
+
+	var testCases = []struct {
+		description string
+		schema      string
+		function    string
+		access      bool
+	}{
+		{"Function with the execute permission", "public", "add", true},
+		{"Function without the execute permission", "public", "test", false},
+		{"Function not listed", "public", "remove", false},
+		{"Function of pg_catalog", "pg_catalog", "pg_sleep", false},
+		{"Function of information_schema", "information_schema", "add", false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			require.Equal(t, tc.access, adapter.FunctionPermissions(tc.schema, tc.function))
+		})
+	}
+}
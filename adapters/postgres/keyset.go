@@ -0,0 +1,216 @@
+package postgres
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	afterKey  = "_after"
+	beforeKey = "_before"
+)
+
+// isKeysetPagination returns true when the request paginates with cursors
+func isKeysetPagination(values url.Values) bool {
+	_, after := values[afterKey]
+	_, before := values[beforeKey]
+	return after || before
+}
+
+// keysetCursor returns the cursor sent on _after or _before
+func keysetCursor(values url.Values) (cursor string, before bool, err error) {
+	_, after := values[afterKey]
+	_, before = values[beforeKey]
+	if after && before {
+		err = ErrKeysetBothDirections
+		return
+	}
+	if before {
+		cursor = values.Get(beforeKey)
+		return
+	}
+	cursor = values.Get(afterKey)
+	return
+}
+
+// encodeCursor encodes the values of the _order fields of a row
+func encodeCursor(values []interface{}) (cursor string, err error) {
+	byt, err := json.Marshal(values)
+	if err != nil {
+		return
+	}
+	cursor = base64.RawURLEncoding.EncodeToString(byt)
+	return
+}
+
+// decodeCursor decodes a cursor created by encodeCursor, numbers are kept
+// as text so that postgres casts them to the column type
+func decodeCursor(cursor string) (values []interface{}, err error) {
+	byt, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		err = errors.Wrap(ErrInvalidCursor, err.Error())
+		return
+	}
+	decoder := json.NewDecoder(bytes.NewReader(byt))
+	decoder.UseNumber()
+	if err = decoder.Decode(&values); err != nil {
+		err = errors.Wrap(ErrInvalidCursor, err.Error())
+		return
+	}
+	for i, v := range values {
+		if n, ok := v.(json.Number); ok {
+			values[i] = n.String()
+		}
+	}
+	return
+}
+
+// KeysetByRequest implements keyset (cursor) pagination
+//
+// returns the condition that selects the rows after (_after) or
+// before (_before) the given cursor, following the _order fields
+func (adapter *Postgres) KeysetByRequest(r *http.Request, initialPlaceholderID int) (keysetSyntax string, values []interface{}, err error) {
+	queries := r.URL.Query()
+	if !isKeysetPagination(queries) {
+		return
+	}
+	cursor, before, err := keysetCursor(queries)
+	if err != nil {
+		return
+	}
+	reqOrder := queries.Get("_order")
+	if reqOrder == "" {
+		err = ErrKeysetWithoutOrder
+		return
+	}
+	fields, err := parseOrder(reqOrder)
+	if err != nil {
+		return
+	}
+	for _, field := range fields {
+		if field.name == "" || field.nulls != "" {
+			err = errors.Wrapf(ErrKeysetOrderExpression, "%s", field.expr)
+			return
+		}
+	}
+	if cursor == "" {
+		return
+	}
+	cursorValues, err := decodeCursor(cursor)
+	if err != nil {
+		return
+	}
+	if len(cursorValues) != len(fields) {
+		err = errors.Wrapf(ErrInvalidCursor, "expected %d values", len(fields))
+		return
+	}
+
+	// (a > $1) OR (a = $1 AND b > $2) ... honoring each field direction
+	conditions := make([]string, 0, len(fields))
+	for i, field := range fields {
+		parts := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			parts = append(parts, fmt.Sprintf(`%s = $%d`, quoteIdentifier(fields[j].name), initialPlaceholderID+j))
+		}
+		op := ">"
+		if field.desc != before {
+			op = "<"
+		}
+		parts = append(parts, fmt.Sprintf(`%s %s $%d`, quoteIdentifier(field.name), op, initialPlaceholderID+i))
+		conditions = append(conditions, fmt.Sprintf("(%s)", strings.Join(parts, " AND ")))
+	}
+	keysetSyntax = fmt.Sprintf("(%s)", strings.Join(conditions, " OR "))
+	values = cursorValues
+	return
+}
+
+// KeysetPage prepares a keyset paginated result
+//
+// drops the extra row requested by PaginateIfPossible, restores the
+// order of rows read backwards and returns the cursors of the next and
+// previous pages, empty when there is no such page
+func (adapter *Postgres) KeysetPage(r *http.Request, body []byte) (page []byte, next, prev string, err error) {
+	queries := r.URL.Query()
+	cursor, before, err := keysetCursor(queries)
+	if err != nil {
+		return
+	}
+	fields, err := parseOrder(queries.Get("_order"))
+	if err != nil {
+		return
+	}
+	pageSize, err := pageSizeByRequest(queries)
+	if err != nil {
+		return
+	}
+	rows := make([]json.RawMessage, 0)
+	if err = json.Unmarshal(body, &rows); err != nil {
+		return
+	}
+	hasMore := len(rows) > pageSize
+	if hasMore {
+		rows = rows[:pageSize]
+	}
+	if before {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	page = []byte("[")
+	for i, row := range rows {
+		if i > 0 {
+			page = append(page, ", "...)
+		}
+		page = append(page, row...)
+	}
+	page = append(page, ']')
+	if len(rows) == 0 {
+		return
+	}
+
+	// there is a page on the side we came from unless no cursor was sent,
+	// which means the first page (_after) or the last page (_before)
+	hasNext, hasPrev := hasMore, cursor != ""
+	if before {
+		hasNext, hasPrev = cursor != "", hasMore
+	}
+	if hasNext {
+		next, err = rowCursor(fields, rows[len(rows)-1])
+		if err != nil {
+			return
+		}
+	}
+	if hasPrev {
+		prev, err = rowCursor(fields, rows[0])
+	}
+	return
+}
+
+// rowCursor creates the cursor that points to the given row
+func rowCursor(fields []orderField, row json.RawMessage) (cursor string, err error) {
+	columns := make(map[string]interface{})
+	decoder := json.NewDecoder(bytes.NewReader(row))
+	decoder.UseNumber()
+	if err = decoder.Decode(&columns); err != nil {
+		return
+	}
+	values := make([]interface{}, 0, len(fields))
+	for _, field := range fields {
+		name := field.name[strings.LastIndex(field.name, ".")+1:]
+		name = strings.Trim(name, `"`)
+		value, ok := columns[name]
+		if !ok {
+			err = errors.Wrapf(ErrInvalidCursor, "column %s is not selected", name)
+			return
+		}
+		values = append(values, value)
+	}
+	return encodeCursor(values)
+}
@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/prest/prest/adapters/postgres/statements"
+	pctx "github.com/prest/prest/context"
+)
+
+// primaryKeys caches the columns of the primary keys by database, schema
+// and table, they are read on every request addressed by key
+var primaryKeys = struct {
+	sync.RWMutex
+	columns map[string][]string
+}{columns: make(map[string][]string)}
+
+// PrimaryKeyCtx returns the columns of the primary key of a table in the
+// order of the key, read from pg_index once per table
+func (adapter *Postgres) PrimaryKeyCtx(ctx context.Context, schema, table string) (columns []string, err error) {
+	dbName, ok := ctx.Value(pctx.DBNameKey).(string)
+	if !ok {
+		dbName = adapter.GetDatabase()
+	}
+	key := strings.Join([]string{dbName, schema, table}, ".")
+	primaryKeys.RLock()
+	columns, ok = primaryKeys.columns[key]
+	primaryKeys.RUnlock()
+	if ok {
+		return
+	}
+
+	sc := adapter.QueryCtx(ctx, statements.PrimaryKey, schema, table)
+	if err = sc.Err(); err != nil {
+		return
+	}
+	var rows []struct {
+		Column string `json:"column"`
+	}
+	if err = json.Unmarshal(sc.Bytes(), &rows); err != nil {
+		return
+	}
+	// tables without a primary key are not cached, one may be added
+	if len(rows) == 0 {
+		err = errors.Wrapf(ErrNoPrimaryKey, "%s.%s", schema, table)
+		return
+	}
+	for _, row := range rows {
+		columns = append(columns, row.Column)
+	}
+	primaryKeys.Lock()
+	primaryKeys.columns[key] = columns
+	primaryKeys.Unlock()
+	return
+}
+
+// ClearPrimaryKeys empties the cache of the primary keys, for tables
+// whose key was altered
+func ClearPrimaryKeys() {
+	primaryKeys.Lock()
+	primaryKeys.columns = make(map[string][]string)
+	primaryKeys.Unlock()
+}
+
+// PrimaryKeyWhereCtx returns the condition that matches the row of the
+// primary key sent on the path, the values of composite keys are
+// separated by commas in the order of the key
+func (adapter *Postgres) PrimaryKeyWhereCtx(ctx context.Context, schema, table, pk string, initialPlaceholderID int) (whereSyntax string, values []interface{}, err error) {
+	columns, err := adapter.PrimaryKeyCtx(ctx, schema, table)
+	if err != nil {
+		return
+	}
+	return primaryKeyWhere(columns, pk, initialPlaceholderID)
+}
+
+// primaryKeyWhere matches each column of the key to its value, the value
+// of a single column key is taken as is while the values of composite keys
+// holding commas are double quoted
+func primaryKeyWhere(columns []string, pk string, pid int) (whereSyntax string, values []interface{}, err error) {
+	items := []string{pk}
+	if len(columns) > 1 {
+		if items, err = splitFilterList(pk); err != nil {
+			err = errors.Wrapf(ErrInvalidPrimaryKey, "%s", pk)
+			return
+		}
+		for i, item := range items {
+			items[i] = unquoteFilterValue(strings.TrimSpace(item))
+		}
+	}
+	if len(items) != len(columns) {
+		err = errors.Wrapf(ErrInvalidPrimaryKey, "%s, expected values of %s", pk, strings.Join(columns, ","))
+		return
+	}
+	conditions := make([]string, len(columns))
+	for i, column := range columns {
+		conditions[i] = fmt.Sprintf("%s = $%d", pq.QuoteIdentifier(column), pid+i)
+		values = append(values, items[i])
+	}
+	whereSyntax = strings.Join(conditions, " AND ")
+	return
+}
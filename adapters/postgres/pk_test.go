@@ -0,0 +1,34 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrimaryKeyWhere(t *testing.T) {
+	var testCases = []struct {
+		description string
+		columns     []string
+		pk          string
+		where       string
+		values      []interface{}
+		err         error
+	}{
+		{"Single column", []string{"id"}, "5", `"id" = $3`, []interface{}{"5"}, nil},
+		{"Single column with a comma", []string{"name"}, "a,b", `"name" = $3`, []interface{}{"a,b"}, nil},
+		{"Composite key", []string{"order_id", "line"}, "7,2", `"order_id" = $3 AND "line" = $4`, []interface{}{"7", "2"}, nil},
+		{"Composite key with a quoted value", []string{"name", "year"}, `"a,b",2023`, `"name" = $3 AND "year" = $4`, []interface{}{"a,b", "2023"}, nil},
+		{"Missing value", []string{"order_id", "line"}, "7", "", nil, ErrInvalidPrimaryKey},
+		{"Unbalanced quotes", []string{"name", "year"}, `"a,2023`, "", nil, ErrInvalidPrimaryKey},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			where, values, err := primaryKeyWhere(tc.columns, tc.pk, 3)
+			require.ErrorIs(t, err, tc.err)
+			require.Equal(t, tc.where, where)
+			require.Equal(t, tc.values, values)
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"reflect"
 	"regexp"
 	"sort"
@@ -37,12 +38,13 @@ type Postgres struct{}
 const (
 	pageNumberKey   = "_page"
 	pageSizeKey     = "_page_size"
-	defaultPageSize = 10
+	DefaultPageSize = 10
 	//nolint
 	defaultPageNumber = 1
 )
 
 var removeOperatorRegex *regexp.Regexp
+var jsonPathRegex *regexp.Regexp
 var insertTableNameQuotesRegex *regexp.Regexp
 var insertTableNameRegex *regexp.Regexp
 var groupRegex *regexp.Regexp
@@ -104,6 +106,7 @@ func Load() {
 
 func init() {
 	removeOperatorRegex = regexp.MustCompile(`\$[a-z]+.`)
+	jsonPathRegex = regexp.MustCompile(`->>?`)
 	insertTableNameRegex = regexp.MustCompile(`(?i)INTO\s+([\w|\.|-]*\.)*([\w|-]+)\s*\(`)
 	insertTableNameQuotesRegex = regexp.MustCompile(`(?i)INTO\s+([\w|\.|"|-]*\.)*"([\w|-]+)"\s*\(`)
 	groupRegex = regexp.MustCompile(`\"(.+?)\"`)
@@ -203,102 +206,164 @@ func chkInvalidIdentifier(identifer ...string) bool {
 // WhereByRequest create interface for queries + where
 func (adapter *Postgres) WhereByRequest(r *http.Request, initialPlaceholderID int) (whereSyntax string, values []interface{}, err error) {
 	whereKey := []string{}
-	whereValues := []string{}
-	var value, op string
+	whereValues := []interface{}{}
 
 	pid := initialPlaceholderID
 	for key, val := range r.URL.Query() {
-		if !strings.HasPrefix(key, "_") {
-			for k, v := range val {
-				if v != "" {
-					op = removeOperatorRegex.FindString(v)
-					op = strings.Replace(op, ".", "", -1)
-					if op == "" {
-						op = "$eq"
+		if groupOp, ok := filterGroupKeys[key]; ok {
+			for _, v := range val {
+				var groupSQL string
+				var groupValues []interface{}
+				groupSQL, groupValues, err = filterGroup(groupOp, v, pid, filterCondition)
+				if err != nil {
+					return "", nil, err
+				}
+				whereKey = append(whereKey, groupSQL)
+				whereValues = append(whereValues, groupValues...)
+				pid += len(groupValues)
+			}
+			continue
+		}
+		if strings.HasPrefix(key, "_") {
+			continue
+		}
+		keyInfo := strings.Split(key, ":")
+		for _, v := range val {
+			op, value := splitOperator(v)
+
+			var expr string
+			var exprValues []interface{}
+			if len(keyInfo) > 1 {
+				switch keyInfo[1] {
+				case "jsonb":
+					var jsonKey string
+					jsonKey, err = jsonPathKey(keyInfo[0])
+					if err != nil {
+						return "", nil, err
 					}
-					value = removeOperatorRegex.ReplaceAllString(v, "")
-					op, err = GetQueryOperator(op)
+					expr, exprValues, err = whereExpression(jsonKey, op, value, pid)
 					if err != nil {
-						return
+						return "", nil, err
 					}
-				}
-
-				keyInfo := strings.Split(key, ":")
-
-				if len(keyInfo) > 1 {
-					switch keyInfo[1] {
-					case "jsonb":
-						jsonField := strings.Split(keyInfo[0], "->>")
-						if chkInvalidIdentifier(jsonField[0], jsonField[1]) {
-							err = errors.Wrapf(ErrInvalidIdentifier, "%v", jsonField)
-							return
-						}
-						fields := strings.Split(jsonField[0], ".")
-						jsonField[0] = fmt.Sprintf(`"%s"`, strings.Join(fields, `"."`))
-						whereKey = append(whereKey, fmt.Sprintf(`%s->>'%s' %s $%d`, jsonField[0], jsonField[1], op, pid))
-						values = append(values, value)
-					case "tsquery":
-						tsQueryField := strings.Split(keyInfo[0], "$")
-						tsQuery := fmt.Sprintf(`%s @@ to_tsquery('%s')`, tsQueryField[0], value)
-						if len(tsQueryField) == 2 {
-							tsQuery = fmt.Sprintf(`%s @@ to_tsquery('%s', '%s')`, tsQueryField[0], tsQueryField[1], value)
-						}
-						whereKey = append(whereKey, tsQuery)
-					default:
-						if chkInvalidIdentifier(keyInfo[0]) {
-							err = errors.Wrapf(ErrInvalidIdentifier, "%s", keyInfo[0])
-							return
-						}
+				case "tsquery", "plainto_tsquery", "phraseto_tsquery", "websearch_to_tsquery":
+					var ts textSearch
+					ts, err = parseTextSearch(keyInfo[0], keyInfo[1], value)
+					if err != nil {
+						return "", nil, err
+					}
+					expr, exprValues = ts.where(pid)
+				default:
+					if chkInvalidIdentifier(keyInfo[0]) {
+						err = errors.Wrapf(ErrInvalidIdentifier, "%s", keyInfo[0])
+						return "", nil, err
 					}
-					pid++
 					continue
 				}
-
+			} else {
 				if chkInvalidIdentifier(key) {
 					err = errors.Wrapf(ErrInvalidIdentifier, "%s", key)
-					return
+					return "", nil, err
 				}
-
-				if k == 0 {
-					fields := strings.Split(key, ".")
-					key = fmt.Sprintf(`"%s"`, strings.Join(fields, `"."`))
-				}
-
-				switch op {
-				case "IN", "NOT IN":
-					v := strings.Split(value, ",")
-					keyParams := make([]string, len(v))
-					for i := 0; i < len(v); i++ {
-						whereValues = append(whereValues, v[i])
-						keyParams[i] = fmt.Sprintf(`$%d`, pid+i)
-					}
-					pid += len(v)
-					whereKey = append(whereKey, fmt.Sprintf(`%s %s (%s)`, key, op, strings.Join(keyParams, ",")))
-				case "ANY", "SOME", "ALL":
-					whereKey = append(whereKey, fmt.Sprintf(`%s = %s ($%d)`, key, op, pid))
-					whereValues = append(whereValues, formatters.FormatArray(strings.Split(value, ",")))
-					pid++
-				case "IS NULL", "IS NOT NULL", "IS TRUE", "IS NOT TRUE", "IS FALSE", "IS NOT FALSE":
-					whereKey = append(whereKey, fmt.Sprintf(`%s %s`, key, op))
-				default: // "=", "!=", ">", ">=", "<", "<="
-					whereKey = append(whereKey, fmt.Sprintf(`%s %s $%d`, key, op, pid))
-					whereValues = append(whereValues, value)
-					pid++
+				expr, exprValues, err = whereExpression(quoteIdentifier(key), op, value, pid)
+				if err != nil {
+					return "", nil, err
 				}
 			}
+			whereKey = append(whereKey, expr)
+			whereValues = append(whereValues, exprValues...)
+			pid += len(exprValues)
 		}
 	}
 
-	for i := 0; i < len(whereKey); i++ {
-		if whereSyntax == "" {
-			whereSyntax += whereKey[i]
-		} else {
-			whereSyntax += " AND " + whereKey[i]
+	whereSyntax = strings.Join(whereKey, " AND ")
+	if len(whereValues) > 0 {
+		values = whereValues
+	}
+	return
+}
+
+// quoteIdentifier quotes each part of a dotted (aliased) identifier
+func quoteIdentifier(identifier string) string {
+	fields := strings.Split(identifier, ".")
+	return fmt.Sprintf(`"%s"`, strings.Join(fields, `"."`))
+}
+
+// splitOperator splits a filter value like `$gte.10` into the operator
+// and the value, only the first operator is removed so that values can
+// contain text like `$a.` (e.g. jsonpath), the operator defaults to $eq
+func splitOperator(v string) (op, value string) {
+	loc := removeOperatorRegex.FindStringIndex(v)
+	if loc == nil {
+		return "$eq", v
+	}
+	op = strings.Replace(v[loc[0]:loc[1]], ".", "", -1)
+	value = v[:loc[0]] + v[loc[1]:]
+	return
+}
+
+// jsonPathKey builds the SQL of a jsonb path like `data->a->>b`, integer
+// keys are array indexes
+func jsonPathKey(path string) (key string, err error) {
+	ops := jsonPathRegex.FindAllString(path, -1)
+	keys := jsonPathRegex.Split(path, -1)
+	if chkInvalidIdentifier(keys[0]) {
+		err = errors.Wrapf(ErrInvalidIdentifier, "%s", path)
+		return
+	}
+	key = quoteIdentifier(keys[0])
+	for i, k := range keys[1:] {
+		if index, convErr := strconv.Atoi(k); convErr == nil {
+			key = fmt.Sprintf(`%s%s%d`, key, ops[i], index)
+			continue
 		}
+		if chkInvalidIdentifier(k) {
+			err = errors.Wrapf(ErrInvalidIdentifier, "%s", path)
+			return "", err
+		}
+		key = fmt.Sprintf(`%s%s'%s'`, key, ops[i], k)
 	}
+	return
+}
 
-	for i := 0; i < len(whereValues); i++ {
-		values = append(values, whereValues[i])
+// whereExpression builds the condition for an already quoted key, the
+// returned values match the placeholders used starting at pid
+func whereExpression(key, op, value string, pid int) (expr string, values []interface{}, err error) {
+	sqlOp, err := GetQueryOperator(op)
+	if err != nil {
+		return
+	}
+	// array and range operators share the SQL operator of other types
+	switch strings.TrimPrefix(op, "$") {
+	case "overlap", "arraycontains":
+		expr = fmt.Sprintf(`%s %s $%d`, key, sqlOp, pid)
+		values = append(values, formatters.FormatArray(strings.Split(value, ",")))
+		return
+	case "rangecontains":
+		// a single element is contained in the same way as the range [v,v]
+		if !strings.HasPrefix(value, "[") && !strings.HasPrefix(value, "(") {
+			value = fmt.Sprintf(`[%s,%s]`, value, value)
+		}
+	}
+	switch sqlOp {
+	case "IN", "NOT IN":
+		v := strings.Split(value, ",")
+		keyParams := make([]string, len(v))
+		for i := 0; i < len(v); i++ {
+			values = append(values, v[i])
+			keyParams[i] = fmt.Sprintf(`$%d`, pid+i)
+		}
+		expr = fmt.Sprintf(`%s %s (%s)`, key, sqlOp, strings.Join(keyParams, ","))
+	case "ANY", "SOME", "ALL":
+		expr = fmt.Sprintf(`%s = %s ($%d)`, key, sqlOp, pid)
+		values = append(values, formatters.FormatArray(strings.Split(value, ",")))
+	case "?&", "?|": // jsonb has all/any keys
+		expr = fmt.Sprintf(`%s %s $%d`, key, sqlOp, pid)
+		values = append(values, formatters.FormatArray(strings.Split(value, ",")))
+	case "IS NULL", "IS NOT NULL", "IS TRUE", "IS NOT TRUE", "IS FALSE", "IS NOT FALSE":
+		expr = fmt.Sprintf(`%s %s`, key, sqlOp)
+	default: // "=", "!=", ">", ">=", "<", "<=", "@>", "<@", "?", "@?"
+		expr = fmt.Sprintf(`%s %s $%d`, key, sqlOp, pid)
+		values = append(values, value)
 	}
 	return
 }
@@ -517,51 +582,101 @@ func (adapter *Postgres) SchemaClause(req *http.Request) (query string, hasCount
 	return
 }
 
+// joinTypes maps the join types accepted on _join to SQL
+var joinTypes = map[string]string{
+	"inner": "INNER",
+	"left":  "LEFT",
+	"right": "RIGHT",
+	"full":  "FULL",
+	"outer": "FULL OUTER",
+}
+
 // JoinByRequest implements join in queries
+//
+// every _join query string adds a join, in the order they were sent, the
+// joined table is aliased with `type:table:alias:field:operator:field`
 func (adapter *Postgres) JoinByRequest(r *http.Request) (values []string, err error) {
-	queries := r.URL.Query()
-
-	if queries.Get("_join") == "" {
-		return
+	for _, reqJoin := range r.URL.Query()["_join"] {
+		if reqJoin == "" {
+			continue
+		}
+		var joinQuery string
+		joinQuery, err = adapter.joinClause(r, reqJoin)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, joinQuery)
 	}
+	return
+}
 
-	joinArgs := strings.Split(queries.Get("_join"), ":")
-
-	if len(joinArgs) != 5 {
+// joinClause builds the SQL of a single _join query string, the soft
+// deleted rows of the joined table are not matched
+func (adapter *Postgres) joinClause(r *http.Request, reqJoin string) (joinQuery string, err error) {
+	joinArgs := strings.Split(reqJoin, ":")
+	var alias string
+	switch len(joinArgs) {
+	case 5:
+	case 6:
+		alias = joinArgs[2]
+		joinArgs = append(joinArgs[:2], joinArgs[3:]...)
+	default:
 		err = ErrJoinInvalidNumberOfArgs
 		return
 	}
 
+	joinType, ok := joinTypes[strings.ToLower(joinArgs[0])]
+	if !ok {
+		err = errors.Wrapf(ErrInvalidJoinClause, "unknown join type %s", joinArgs[0])
+		return
+	}
 	if chkInvalidIdentifier(joinArgs[1], joinArgs[2], joinArgs[4]) {
 		err = ErrInvalidIdentifier
 		return
 	}
+	if alias != "" && (chkInvalidIdentifier(alias) || strings.Contains(alias, ".")) {
+		err = errors.Wrapf(ErrInvalidIdentifier, "%s", alias)
+		return
+	}
 
 	op, err := GetQueryOperator(joinArgs[3])
 	if err != nil {
 		return
 	}
-	errJoin := ErrInvalidJoinClause
-	if joinWith := strings.Split(joinArgs[1], "."); len(joinWith) == 2 {
-		joinArgs[1] = fmt.Sprintf(`%s"."%s`, joinWith[0], joinWith[1])
-	}
-	spl := strings.Split(joinArgs[2], ".")
-	if len(spl) != 2 {
-		err = errJoin
+	joinWith := strings.Split(joinArgs[1], ".")
+	if len(joinWith) > 2 {
+		err = ErrInvalidJoinClause
 		return
 	}
-	splj := strings.Split(joinArgs[4], ".")
-	if len(splj) != 2 {
-		err = errJoin
+	for _, field := range []string{joinArgs[2], joinArgs[4]} {
+		if spl := strings.Split(field, "."); len(spl) < 2 || len(spl) > 3 {
+			err = ErrInvalidJoinClause
+			return
+		}
+	}
+	joinTable := joinWith[len(joinWith)-1]
+	if !adapter.TablePermissions(joinTable, "read") {
+		err = errors.Wrapf(ErrJoinNotPermitted, "%s", joinTable)
 		return
 	}
-	joinQuery := fmt.Sprintf(` %s JOIN "%s" ON "%s"."%s" %s "%s"."%s" `, strings.ToUpper(joinArgs[0]), joinArgs[1], spl[0], spl[1], op, splj[0], splj[1])
-	values = append(values, joinQuery)
+
+	tableSQL := quoteIdentifier(joinArgs[1])
+	qualifier := tableSQL
+	if alias != "" {
+		tableSQL = fmt.Sprintf(`%s AS "%s"`, tableSQL, alias)
+		qualifier = fmt.Sprintf(`"%s"`, alias)
+	}
+	on := fmt.Sprintf(`%s %s %s`, quoteIdentifier(joinArgs[2]), op, quoteIdentifier(joinArgs[4]))
+	if notDeleted := adapter.relatedSoftDeleteWhere(r, qualifier, joinTable); notDeleted != "" {
+		on = fmt.Sprintf(`%s AND %s`, on, notDeleted)
+	}
+	joinQuery = fmt.Sprintf(` %s JOIN %s ON %s `, joinType, tableSQL, on)
 	return
 }
 
-// SelectFields query
-func (adapter *Postgres) SelectFields(fields []string) (sql string, err error) {
+// SelectFields query, the values of the group functions are bound as
+// placeholders numbered starting at initialPlaceholderID
+func (adapter *Postgres) SelectFields(fields []string, initialPlaceholderID int) (sql string, values []interface{}, err error) {
 	if len(fields) == 0 {
 		err = ErrMustSelectOneField
 		return
@@ -569,10 +684,16 @@ func (adapter *Postgres) SelectFields(fields []string) (sql string, err error) {
 	var aux []string
 
 	for _, field := range fields {
-		groupFunc, _ := NormalizeGroupFunction(field)
+		groupFunc, groupValues, _ := NormalizeGroupFunction(field, initialPlaceholderID+len(values))
 
 		if groupFunc != "" {
 			aux = append(aux, groupFunc)
+			values = append(values, groupValues...)
+			continue
+		}
+		// group functions normalized by columnsByRequest
+		if groupFuncSQLRegex.MatchString(field) {
+			aux = append(aux, field)
 			continue
 		}
 
@@ -597,36 +718,133 @@ func (adapter *Postgres) SelectFields(fields []string) (sql string, err error) {
 	return
 }
 
-// OrderByRequest implements ORDER BY in queries
-func (adapter *Postgres) OrderByRequest(r *http.Request) (values string, err error) {
-	queries := r.URL.Query()
-	reqOrder := queries.Get("_order")
+// orderFunctions is the allow-list of functions accepted on _order
+var orderFunctions = map[string]bool{
+	"lower":       true,
+	"upper":       true,
+	"length":      true,
+	"char_length": true,
+	"abs":         true,
+	"trim":        true,
+	"date":        true,
+}
 
-	if reqOrder != "" {
-		values = " ORDER BY "
-		orderingArr := strings.Split(reqOrder, ",")
+// orderNulls maps the suffixes of the _order fields to the placement of
+// the nulls
+var orderNulls = map[string]string{
+	".nullsfirst": "NULLS FIRST",
+	".nullslast":  "NULLS LAST",
+}
 
-		for i, field := range orderingArr {
-			if chkInvalidIdentifier(field) {
-				err = ErrInvalidIdentifier
-				values = ""
-				return
-			}
-			f := strings.Split(field, ".")
-			field = fmt.Sprintf(`"%s"`, strings.Join(f, `"."`))
-			if strings.HasPrefix(field, `"-`) {
-				field = strings.Replace(field, `"-`, `"`, 1)
-				field = fmt.Sprintf(`%s DESC`, field)
-			}
+// orderFunctionRegex matches a function on the _order query string, e.g.
+// lower(name)
+var orderFunctionRegex = regexp.MustCompile(`^(\w+)\((.+)\)$`)
 
-			values = fmt.Sprintf("%s %s", values, field)
+// orderField is a field sent on the _order query string
+type orderField struct {
+	// name is the column, empty when ordering by an expression
+	name  string
+	expr  string
+	desc  bool
+	nulls string
+}
+
+// sql returns the ORDER BY syntax of the field
+func (f orderField) sql() string {
+	orderBy := f.expr
+	if f.desc {
+		orderBy = fmt.Sprintf(`%s DESC`, orderBy)
+	}
+	if f.nulls != "" {
+		orderBy = fmt.Sprintf(`%s %s`, orderBy, f.nulls)
+	}
+	return orderBy
+}
 
-			// if have next order, append a comma
-			if i < len(orderingArr)-1 {
-				values = fmt.Sprintf("%s ,", values)
+// parseOrder parses the fields of the _order query string, a `-` prefix
+// means descending order and a `.nullsfirst` or `.nullslast` suffix sets
+// the placement of the nulls. Fields can be json paths (`data->>key`) or
+// the argument of a function of orderFunctions (`lower(name)`)
+func parseOrder(reqOrder string) (fields []orderField, err error) {
+	items, err := splitFilterList(reqOrder)
+	if err != nil {
+		err = errors.Wrapf(ErrInvalidIdentifier, "%s", reqOrder)
+		return
+	}
+	for _, field := range items {
+		var f orderField
+		item := field
+		if strings.HasPrefix(item, "-") {
+			item = strings.TrimPrefix(item, "-")
+			f.desc = true
+		}
+		for suffix, nulls := range orderNulls {
+			if strings.HasSuffix(strings.ToLower(item), suffix) {
+				item = item[:len(item)-len(suffix)]
+				f.nulls = nulls
 			}
 		}
+		f.expr, err = orderExpression(item)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s", field)
+		}
+		if f.expr == quoteIdentifier(item) {
+			f.name = item
+		}
+		fields = append(fields, f)
+	}
+	return
+}
+
+// orderExpression builds the SQL of a column, a json path or a function
+// of the allow-list
+func orderExpression(item string) (expr string, err error) {
+	if m := orderFunctionRegex.FindStringSubmatch(item); m != nil {
+		fn := strings.ToLower(m[1])
+		if !orderFunctions[fn] {
+			err = errors.Wrapf(ErrInvalidOrderFunction, "%s", m[1])
+			return
+		}
+		var arg string
+		if arg, err = orderExpression(m[2]); err != nil {
+			return
+		}
+		expr = fmt.Sprintf(`%s(%s)`, fn, arg)
+		return
+	}
+	if jsonPathRegex.MatchString(item) {
+		return jsonPathKey(item)
+	}
+	if chkInvalidIdentifier(item) || strings.ContainsAny(item, `()"`) {
+		err = ErrInvalidIdentifier
+		return
+	}
+	expr = quoteIdentifier(item)
+	return
+}
+
+// OrderByRequest implements ORDER BY in queries
+//
+// when paginating backwards with _before the order is reversed
+func (adapter *Postgres) OrderByRequest(r *http.Request) (values string, err error) {
+	queries := r.URL.Query()
+	reqOrder := queries.Get("_order")
+	if reqOrder == "" {
+		return
+	}
+	fields, err := parseOrder(reqOrder)
+	if err != nil {
+		return
+	}
+	_, before := queries[beforeKey]
+	orderBy := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if before {
+			field.desc = !field.desc
+		}
+		orderBy = append(orderBy, field.sql())
 	}
+	values = fmt.Sprintf(" ORDER BY %s", strings.Join(orderBy, ", "))
 	return
 }
 
@@ -660,15 +878,10 @@ func (adapter *Postgres) CountByRequest(req *http.Request) (countQuery string, e
 //
 // allows setting timeout
 func (adapter *Postgres) QueryCtx(ctx context.Context, SQL string, params ...interface{}) (sc adapters.Scanner) {
-	// use the db_name that was set on request to avoid runtime collisions
-	db, err := getDBFromCtx(ctx)
-	if err != nil {
-		log.Errorln(err)
-		return &scanner.PrestScanner{Error: err}
-	}
 	SQL = fmt.Sprintf("SELECT jsonb_agg(s) FROM (%s) s", SQL)
 	log.Debugln("generated SQL:", SQL, " parameters: ", params)
-	p, err := Prepare(db, SQL)
+	// use the db_name that was set on request to avoid runtime collisions
+	p, err := prepareCtx(ctx, SQL)
 	if err != nil {
 		log.Errorln(err)
 		return &scanner.PrestScanner{Error: err}
@@ -740,13 +953,8 @@ func (adapter *Postgres) QueryCount(SQL string, params ...interface{}) (sc adapt
 
 // QueryCount process queries with count
 func (adapter *Postgres) QueryCountCtx(ctx context.Context, SQL string, params ...interface{}) (sc adapters.Scanner) {
-	db, err := getDBFromCtx(ctx)
-	if err != nil {
-		log.Errorln(err)
-		return &scanner.PrestScanner{Error: err}
-	}
 	log.Debugln("generated SQL:", SQL, " parameters: ", params)
-	p, err := Prepare(db, SQL)
+	p, err := prepareCtx(ctx, SQL)
 	if err != nil {
 		log.Errorln(err)
 		return &scanner.PrestScanner{Error: err}
@@ -770,8 +978,24 @@ func (adapter *Postgres) QueryCountCtx(ctx context.Context, SQL string, params .
 }
 
 // PaginateIfPossible when passing non-valid paging parameters (conversion to integer) the query will be made with default value
+//
+// on keyset pagination (_after or _before) one row more than the page
+// size is requested, it tells KeysetPage whether there is another page
 func (adapter *Postgres) PaginateIfPossible(r *http.Request) (paginatedQuery string, err error) {
 	values := r.URL.Query()
+	if isKeysetPagination(values) {
+		if _, ok := values[pageNumberKey]; ok {
+			err = ErrKeysetWithPage
+			return
+		}
+		var pageSize int
+		pageSize, err = pageSizeByRequest(values)
+		if err != nil {
+			return
+		}
+		paginatedQuery = fmt.Sprintf("LIMIT %d", pageSize+1)
+		return
+	}
 	if _, ok := values[pageNumberKey]; !ok {
 		paginatedQuery = ""
 		return
@@ -780,14 +1004,20 @@ func (adapter *Postgres) PaginateIfPossible(r *http.Request) (paginatedQuery str
 	if err != nil {
 		return
 	}
-	pageSize := defaultPageSize
+	pageSize, err := pageSizeByRequest(values)
+	if err != nil {
+		return
+	}
+	return template.LimitOffset(fmt.Sprint(pageNumber), fmt.Sprint(pageSize))
+}
+
+// pageSizeByRequest returns the _page_size query string or its default value
+func pageSizeByRequest(values url.Values) (pageSize int, err error) {
+	pageSize = DefaultPageSize
 	if size, ok := values[pageSizeKey]; ok {
 		pageSize, err = strconv.Atoi(size[0])
-		if err != nil {
-			return
-		}
 	}
-	return template.LimitOffset(fmt.Sprint(pageNumber), fmt.Sprint(pageSize))
+	return
 }
 
 // BatchInsertCopy execute batch insert sql into a table unsing copy
@@ -858,17 +1088,15 @@ func (adapter *Postgres) BatchInsertCopy(dbname, schema, table string, keys []st
 
 // BatchInsertCopyCtx execute batch insert sql into a table unsing copy
 func (adapter *Postgres) BatchInsertCopyCtx(ctx context.Context, dbname, schema, table string, keys []string, values ...interface{}) (sc adapters.Scanner) {
-	db, err := getDBFromCtx(ctx)
-	if err != nil {
-		log.Errorln(err)
-		return &scanner.PrestScanner{Error: err}
-	}
-	tx, err := db.Begin()
+	tx, owned, err := beginTxCtx(ctx)
 	if err != nil {
 		log.Errorln(err)
 		return &scanner.PrestScanner{Error: err}
 	}
 	defer func() {
+		if !owned {
+			return
+		}
 		var txerr error
 		if err != nil {
 			txerr = tx.Rollback()
@@ -893,28 +1121,7 @@ func (adapter *Postgres) BatchInsertCopyCtx(ctx context.Context, dbname, schema,
 			}
 		}
 	}
-	stmt, err := tx.Prepare(pq.CopyInSchema(schema, table, keys...))
-	if err != nil {
-		log.Errorln(err)
-		return &scanner.PrestScanner{Error: err}
-	}
-	initOffSet := 0
-	limitOffset := len(keys)
-	for limitOffset <= len(values) {
-		_, err = stmt.Exec(values[initOffSet:limitOffset]...)
-		if err != nil {
-			log.Errorln(err)
-			return &scanner.PrestScanner{Error: err}
-		}
-		initOffSet = limitOffset
-		limitOffset += len(keys)
-	}
-	_, err = stmt.Exec()
-	if err != nil {
-		log.Errorln(err)
-		return &scanner.PrestScanner{Error: err}
-	}
-	err = stmt.Close()
+	err = copyIn(tx, pq.CopyInSchema(schema, table, keys...), keys, values)
 	if err != nil {
 		log.Errorln(err)
 		return &scanner.PrestScanner{Error: err}
@@ -969,12 +1176,16 @@ func (adapter *Postgres) BatchInsertValues(SQL string, values ...interface{}) (s
 
 // BatchInsertValuesCtx execute batch insert sql into a table unsing multi values
 func (adapter *Postgres) BatchInsertValuesCtx(ctx context.Context, SQL string, values ...interface{}) (sc adapters.Scanner) {
-	db, err := getDBFromCtx(ctx)
-	if err != nil {
-		log.Errorln(err)
-		return &scanner.PrestScanner{Error: err}
+	var db *sqlx.DB
+	tx := getTxFromCtx(ctx)
+	if tx == nil {
+		var err error
+		if db, err = getDBFromCtx(ctx); err != nil {
+			log.Errorln(err)
+			return &scanner.PrestScanner{Error: err}
+		}
 	}
-	stmt, err := adapter.fullInsert(db, nil, SQL)
+	stmt, err := adapter.fullInsert(db, tx, SQL)
 	if err != nil {
 		log.Errorln(err)
 		return &scanner.PrestScanner{Error: err}
@@ -985,6 +1196,7 @@ func (adapter *Postgres) BatchInsertValuesCtx(ctx context.Context, SQL string, v
 		log.Errorln(err)
 		return &scanner.PrestScanner{Error: err}
 	}
+	defer rows.Close()
 	for rows.Next() {
 		if err = rows.Err(); err != nil {
 			if err != nil {
@@ -1042,6 +1254,9 @@ func (adapter *Postgres) Insert(SQL string, params ...interface{}) (sc adapters.
 
 // InsertCtx execute insert sql into a table
 func (adapter *Postgres) InsertCtx(ctx context.Context, SQL string, params ...interface{}) (sc adapters.Scanner) {
+	if tx := getTxFromCtx(ctx); tx != nil {
+		return adapter.InsertWithTransaction(tx, SQL, params...)
+	}
 	db, err := getDBFromCtx(ctx)
 	if err != nil {
 		log.Errorln(err)
@@ -1064,6 +1279,10 @@ func (adapter *Postgres) insert(db *sqlx.DB, tx *sql.Tx, SQL string, params ...i
 	log.Debugln(SQL, " parameters: ", params)
 	var jsonData []byte
 	err = stmt.QueryRow(params...).Scan(&jsonData)
+	// the row skipped by ON CONFLICT DO NOTHING is not returned
+	if errors.Is(err, sql.ErrNoRows) && strings.HasSuffix(SQL, "DO NOTHING") {
+		err = nil
+	}
 	return &scanner.PrestScanner{
 		Error: err,
 		Buff:  bytes.NewBuffer(jsonData),
@@ -1082,6 +1301,9 @@ func (adapter *Postgres) Delete(SQL string, params ...interface{}) (sc adapters.
 
 // Delete execute delete sql into a table
 func (adapter *Postgres) DeleteCtx(ctx context.Context, SQL string, params ...interface{}) (sc adapters.Scanner) {
+	if tx := getTxFromCtx(ctx); tx != nil {
+		return adapter.DeleteWithTransaction(tx, SQL, params...)
+	}
 	db, err := getDBFromCtx(ctx)
 	if err != nil {
 		log.Errorln(err)
@@ -1109,7 +1331,12 @@ func (adapter *Postgres) delete(db *sqlx.DB, tx *sql.Tx, SQL string, params ...i
 		return &scanner.PrestScanner{Error: err}
 	}
 	if strings.Contains(SQL, "RETURNING") {
-		rows, _ := stmt.Query(params...)
+		rows, err := stmt.Query(params...)
+		if err != nil {
+			log.Errorln(err)
+			return &scanner.PrestScanner{Error: err}
+		}
+		defer rows.Close()
 		cols, _ := rows.Columns()
 		var data []map[string]interface{}
 		for rows.Next() {
@@ -1173,6 +1400,9 @@ func (adapter *Postgres) Update(SQL string, params ...interface{}) (sc adapters.
 
 // Update execute update sql into a table
 func (adapter *Postgres) UpdateCtx(ctx context.Context, SQL string, params ...interface{}) (sc adapters.Scanner) {
+	if tx := getTxFromCtx(ctx); tx != nil {
+		return adapter.UpdateWithTransaction(tx, SQL, params...)
+	}
 	db, err := getDBFromCtx(ctx)
 	if err != nil {
 		log.Errorln(err)
@@ -1200,7 +1430,12 @@ func (adapter *Postgres) update(db *sqlx.DB, tx *sql.Tx, SQL string, params ...i
 	}
 	log.Debugln("generated SQL:", SQL, " parameters: ", params)
 	if strings.Contains(SQL, "RETURNING") {
-		rows, _ := stmt.Query(params...)
+		rows, err := stmt.Query(params...)
+		if err != nil {
+			log.Errorln(err)
+			return &scanner.PrestScanner{Error: err}
+		}
+		defer rows.Close()
 		cols, _ := rows.Columns()
 		var data []map[string]interface{}
 		for rows.Next() {
@@ -1300,6 +1535,26 @@ func GetQueryOperator(op string) (string, error) {
 		return "NOT LIKE", nil
 	case "nilike":
 		return "NOT ILIKE", nil
+	// jsonb features
+	case "contains":
+		return "@>", nil
+	case "containedby":
+		return "<@", nil
+	case "haskey":
+		return "?", nil
+	case "haskeys":
+		return "?&", nil
+	case "hasanykey":
+		return "?|", nil
+	case "jsonpath":
+		return "@?", nil
+	// array and range features
+	case "overlap", "rangeoverlap":
+		return "&&", nil
+	case "arraycontains", "rangecontains":
+		return "@>", nil
+	case "rangeadjacent":
+		return "-|-", nil
 	// ltree features
 	case "ltreelanc":
 		return "@>", nil
@@ -1385,6 +1640,13 @@ func (adapter *Postgres) FieldsPermissions(r *http.Request, table string, op str
 		err = fmt.Errorf("error on parse columns from request: %s", err)
 		return
 	}
+	fields = permittedFields(table, op, cols)
+	return
+}
+
+// permittedFields filters the columns by the fields allowed on the table,
+// all allowed fields are returned when no column was asked for
+func permittedFields(table, op string, cols []string) (fields []string) {
 	restrict := config.PrestConf.AccessConf.Restrict
 	if !restrict || op == "delete" {
 		if len(cols) > 0 {
@@ -1415,6 +1677,10 @@ func (adapter *Postgres) FieldsPermissions(r *http.Request, table string, op str
 func checkField(col string, fields []string) (p string) {
 	// regex get field from func group
 	fieldName := groupRegex.FindStringSubmatch(col)
+	// group functions not normalized yet, `func:field[:alias]`
+	if params := strings.Split(col, ":"); len(params) > 1 {
+		fieldName = params[:2]
+	}
 	for _, f := range fields {
 		if len(fieldName) == 2 && fieldName[1] == f {
 			p = col
@@ -1442,7 +1708,13 @@ func normalizeAll(cols []string) (pCols []string, err error) {
 
 func normalizeColumn(col string) (gf string, err error) {
 	if strings.Contains(col, ":") {
-		gf, err = NormalizeGroupFunction(col)
+		var values []interface{}
+		gf, values, err = NormalizeGroupFunction(col, 1)
+		// the functions taking a value are normalized by SelectFields,
+		// which binds it
+		if len(values) > 0 {
+			gf = col
+		}
 		return
 	}
 	gf = col
@@ -1457,7 +1729,7 @@ func columnsByRequest(r *http.Request) (columns []string, err error) {
 		cArgs := strings.Split(j, ",")
 		columns = append(columns, cArgs...)
 	}
-	if queries.Get("_groupby") != "" {
+	if queries.Get("_groupby") != "" || queries.Get("_bucket") != "" {
 		columns, err = normalizeAll(columns)
 		if err != nil {
 			return
@@ -1479,74 +1751,151 @@ func (adapter *Postgres) DistinctClause(r *http.Request) (distinctQuery string,
 }
 
 // GroupByClause get params in request to add group by clause
-func (adapter *Postgres) GroupByClause(r *http.Request) (groupBySQL string) {
+//
+// `->>having:` adds the HAVING conditions, `func:field:$op:value` joined
+// with AND, groups like `or(...)` are nested as in the filter groups. An
+// invalid HAVING clause is ignored, the values are bound as placeholders
+// numbered starting at initialPlaceholderID
+func (adapter *Postgres) GroupByClause(r *http.Request, initialPlaceholderID int) (groupBySQL string, values []interface{}) {
 	queries := r.URL.Query()
 	groupQuery := queries.Get("_groupby")
 	if groupQuery == "" {
 		return
 	}
 
-	if strings.Contains(groupQuery, "->>having") {
-		params := strings.Split(groupQuery, ":")
-		groupFieldQuery := strings.Split(groupQuery, "->>having")
-
-		fields := strings.Split(groupFieldQuery[0], ",")
-		for i, field := range fields {
-			f := strings.Split(field, ".")
-			fields[i] = fmt.Sprintf(`"%s"`, strings.Join(f, `"."`))
-		}
-		groupFieldQuery[0] = strings.Join(fields, ",")
-		if len(params) != 5 {
-			groupBySQL = fmt.Sprintf(statements.GroupBy, groupFieldQuery[0])
-			return
-		}
-		// groupFunc, field, condition, conditionValue string
-		groupFunc, err := NormalizeGroupFunction(fmt.Sprintf("%s:%s", params[1], params[2]))
-		if err != nil {
-			groupBySQL = fmt.Sprintf(statements.GroupBy, groupFieldQuery[0])
-			return
-		}
-
-		operator, err := GetQueryOperator(params[3])
-		if err != nil {
-			groupBySQL = fmt.Sprintf(statements.GroupBy, groupFieldQuery[0])
-			return
-		}
-
-		havingQuery := fmt.Sprintf(statements.Having, groupFunc, operator, params[4])
-		groupBySQL = fmt.Sprintf("%s %s", fmt.Sprintf(statements.GroupBy, groupFieldQuery[0]), havingQuery)
+	groupFields, having, hasHaving := strings.Cut(groupQuery, "->>having")
+	fields := strings.Split(groupFields, ",")
+	for i, field := range fields {
+		fields[i] = quoteIdentifier(field)
+	}
+	groupBySQL = fmt.Sprintf(statements.GroupBy, strings.Join(fields, ","))
+	if !hasHaving {
 		return
 	}
-	fields := strings.Split(groupQuery, ",")
-	for i, field := range fields {
-		f := strings.Split(field, ".")
-		fields[i] = fmt.Sprintf(`"%s"`, strings.Join(f, `"."`))
+
+	havingSQL, values, err := filterGroup("and", "("+strings.TrimPrefix(having, ":")+")", initialPlaceholderID, havingCondition)
+	if err != nil {
+		log.Debugln("invalid having clause:", err)
+		return groupBySQL, nil
 	}
-	groupQuery = strings.Join(fields, ",")
-	groupBySQL = fmt.Sprintf(statements.GroupBy, groupQuery)
+	// the conditions are not enclosed by the parentheses of the group
+	havingSQL = havingSQL[1 : len(havingSQL)-1]
+	groupBySQL = fmt.Sprintf("%s %s", groupBySQL, fmt.Sprintf(statements.Having, havingSQL))
 	return
 }
 
+// havingCondition builds the SQL of a single `func:field:$op:value`
+// HAVING condition
+func havingCondition(item string, pid int) (expr string, values []interface{}, err error) {
+	params := strings.SplitN(strings.TrimSpace(item), ":", 4)
+	if len(params) < 3 {
+		err = errors.Wrapf(ErrInvalidHaving, "%s", item)
+		return
+	}
+	groupFunc, groupValues, err := NormalizeGroupFunction(fmt.Sprintf("%s:%s", params[0], params[1]), pid)
+	if err != nil {
+		return
+	}
+	var value string
+	if len(params) == 4 {
+		value = params[3]
+	}
+	expr, values, err = whereExpression(groupFunc, params[2], unquoteFilterValue(value), pid+len(groupValues))
+	return expr, append(groupValues, values...), err
+}
+
+// groupFunctions maps the group functions accepted on the request to the
+// SQL aggregate functions
+var groupFunctions = map[string]string{
+	"sum":             "SUM",
+	"avg":             "AVG",
+	"max":             "MAX",
+	"min":             "MIN",
+	"stddev":          "STDDEV",
+	"variance":        "VARIANCE",
+	"count":           "COUNT",
+	"countdistinct":   "COUNT",
+	"array_agg":       "ARRAY_AGG",
+	"string_agg":      "STRING_AGG",
+	"bool_and":        "BOOL_AND",
+	"bool_or":         "BOOL_OR",
+	"percentile_cont": "PERCENTILE_CONT",
+}
+
+var (
+	// groupFuncRegex matches a group function with an optional argument,
+	// e.g. percentile_cont(0.5)
+	groupFuncRegex = regexp.MustCompile(`^(\w+)(?:\((.*)\))?$`)
+	// groupFuncSQLRegex matches the SQL built by NormalizeGroupFunction
+	groupFuncSQLRegex = regexp.MustCompile(`^(?:SUM|AVG|MAX|MIN|STDDEV|VARIANCE|COUNT|ARRAY_AGG|STRING_AGG|BOOL_AND|BOOL_OR|PERCENTILE_CONT)` +
+		`\((?:\*|(?:DISTINCT )?"[^"]+"(?:\."[^"]+")*|[0-9.]+)\)` +
+		`(?: WITHIN GROUP \(ORDER BY "[^"]+"(?:\."[^"]+")*\))?(?: AS "[^"]+")?$`)
+)
+
 // NormalizeGroupFunction normalize url params values to sql group functions
-func NormalizeGroupFunction(paramValue string) (groupFuncSQL string, err error) {
+//
+// the values are `func:field[:alias]`, string_agg takes an optional
+// delimiter (`string_agg(|):field`, `,` by default), bound as the
+// placeholder initialPlaceholderID, and percentile_cont the fraction
+// (`percentile_cont(0.5):field`)
+func NormalizeGroupFunction(paramValue string, initialPlaceholderID int) (groupFuncSQL string, groupValues []interface{}, err error) {
 	values := strings.Split(paramValue, ":")
-	groupFunc := strings.ToUpper(values[0])
-	switch groupFunc {
-	case "SUM", "AVG", "MAX", "MIN", "STDDEV", "VARIANCE":
-		// values[1] it's a field in table
-		v := values[1]
-		if v != "*" {
-			values[1] = fmt.Sprintf(`"%s"`, v)
-		}
-		groupFuncSQL = fmt.Sprintf(`%s(%s)`, groupFunc, values[1])
-		if len(values) == 3 {
-			groupFuncSQL = fmt.Sprintf(`%s AS "%s"`, groupFuncSQL, values[2])
+	m := groupFuncRegex.FindStringSubmatch(values[0])
+	if m == nil {
+		err = errors.Wrapf(ErrInvalidGroupFn, "%s", values[0])
+		return
+	}
+	name, arg := strings.ToLower(m[1]), m[2]
+	groupFunc, ok := groupFunctions[name]
+	if !ok || len(values) < 2 || len(values) > 3 {
+		err = errors.Wrapf(ErrInvalidGroupFn, "%s", strings.ToUpper(values[0]))
+		return
+	}
+	// values[1] it's a field in table
+	field := values[1]
+	if field != "*" {
+		if chkInvalidIdentifier(field) {
+			err = errors.Wrapf(ErrInvalidIdentifier, "%s", field)
+			return
 		}
+		field = quoteIdentifier(field)
+	}
+	if arg != "" && name != "string_agg" && name != "percentile_cont" {
+		err = errors.Wrapf(ErrInvalidGroupFn, "%s", values[0])
 		return
+	}
+
+	switch name {
+	case "countdistinct":
+		if field == "*" {
+			err = errors.Wrapf(ErrInvalidGroupFn, "%s", paramValue)
+			return
+		}
+		groupFuncSQL = fmt.Sprintf(`%s(DISTINCT %s)`, groupFunc, field)
+	case "string_agg":
+		if arg == "" {
+			arg = ","
+		}
+		groupFuncSQL = fmt.Sprintf(`%s(%s, $%d)`, groupFunc, field, initialPlaceholderID)
+		groupValues = append(groupValues, arg)
+	case "percentile_cont":
+		fraction, fErr := strconv.ParseFloat(arg, 64)
+		if fErr != nil || fraction < 0 || fraction > 1 || field == "*" {
+			err = errors.Wrapf(ErrInvalidGroupFn, "%s", paramValue)
+			return
+		}
+		groupFuncSQL = fmt.Sprintf(`%s(%s) WITHIN GROUP (ORDER BY %s)`, groupFunc, strconv.FormatFloat(fraction, 'f', -1, 64), field)
 	default:
-		err = errors.Wrapf(ErrInvalidGroupFn, "%s", groupFunc)
-		return
+		groupFuncSQL = fmt.Sprintf(`%s(%s)`, groupFunc, field)
+	}
+	if len(values) == 3 {
+		if chkInvalidIdentifier(values[2]) || strings.Contains(values[2], `"`) {
+			err = errors.Wrapf(ErrInvalidIdentifier, "%s", values[2])
+			return "", nil, err
+		}
+		groupFuncSQL = fmt.Sprintf(`%s AS "%s"`, groupFuncSQL, values[2])
 	}
+	return
 }
 
 // SetDatabase set the current database name in use
@@ -1685,6 +2034,26 @@ func (adapter *Postgres) ShowTableCtx(ctx context.Context, schema, table string)
 	return adapter.QueryCtx(ctx, query, table, schema)
 }
 
+// ShowTableDetailCtx shows the columns, constraints, indexes and comments
+// of a table as one document read from pg_catalog
+func (adapter *Postgres) ShowTableDetailCtx(ctx context.Context, schema, table string) adapters.Scanner {
+	log.Debugln("generated SQL:", statements.TableDetail, " parameters: ", schema, table)
+	p, err := prepareCtx(ctx, statements.TableDetail)
+	if err != nil {
+		log.Errorln(err)
+		return &scanner.PrestScanner{Error: err}
+	}
+	var jsonData []byte
+	err = p.QueryRowContext(ctx, schema, table).Scan(&jsonData)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = errors.Wrapf(ErrTableNotFound, "%s.%s", schema, table)
+	}
+	return &scanner.PrestScanner{
+		Error: err,
+		Buff:  bytes.NewBuffer(jsonData),
+	}
+}
+
 // GetDatabase returns the current DB name
 func (adapter *Postgres) GetDatabase() string {
 	return connection.GetDatabase()
@@ -1699,3 +2068,40 @@ func getDBFromCtx(ctx context.Context) (db *sqlx.DB, err error) {
 	}
 	return connection.Get()
 }
+
+// getTxFromCtx returns the transaction the request runs in, nil when it
+// runs on its own
+func getTxFromCtx(ctx context.Context) *sql.Tx {
+	tx, _ := ctx.Value(pctx.TxKey).(*sql.Tx)
+	return tx
+}
+
+// beginTxCtx returns the transaction of the request or begins one, owned
+// is true when the caller begins it and has to commit it
+func beginTxCtx(ctx context.Context) (tx *sql.Tx, owned bool, err error) {
+	if tx = getTxFromCtx(ctx); tx != nil {
+		return
+	}
+	db, err := getDBFromCtx(ctx)
+	if err != nil {
+		return
+	}
+	if tx, err = db.BeginTx(ctx, nil); err != nil {
+		return
+	}
+	return tx, true, nil
+}
+
+// prepareCtx prepares the statement on the transaction of the request or
+// on its database
+func prepareCtx(ctx context.Context, SQL string) (stmt *sql.Stmt, err error) {
+	if tx := getTxFromCtx(ctx); tx != nil {
+		return PrepareTx(tx, SQL)
+	}
+	db, err := getDBFromCtx(ctx)
+	if err != nil {
+		log.Errorln(err)
+		return
+	}
+	return Prepare(db, SQL)
+}
@@ -187,7 +187,11 @@ func TestWhereByRequest(t *testing.T) {
 		{"Where by request with not like", "/prest-test/public/test5?name=$nlike.%25val%25&phonenumber=123456", []string{`"name" NOT LIKE $`, `"phonenumber" = $`, " AND "}, []string{"%val%", "123456"}, nil},
 		{"Where by request with not ilike", "/prest-test/public/test5?name=$nilike.%25vAl%25&phonenumber=123456", []string{`"name" NOT ILIKE $`, `"phonenumber" = $`, " AND "}, []string{"%vAl%", "123456"}, nil},
 		{"Where by request with multiple colunm values", "/prest-test/public/table?created_at='$gte.1997-11-03'&created_at='$lte.1997-12-05'", []string{`"created_at" >= $`, ` AND `, `"created_at" <= $`}, []string{`'1997-11-03'`, `'1997-12-05'`}, nil},
-		{"Where by request with tsquery", "/prest-test/public/test5?name:tsquery=prest", []string{`name @@ to_tsquery('prest')`}, []string{`'prest'`}, nil},
+		{"Where by request with tsquery", "/prest-test/public/test5?name:tsquery=prest", []string{`"name" @@ to_tsquery($1)`}, []string{`prest`}, nil},
+		{"Where by request with tsquery and language", "/prest-test/public/test5?name$portuguese:tsquery=prest", []string{`"name" @@ to_tsquery($1::regconfig, $2)`}, []string{`portuguese`, `prest`}, nil},
+		{"Where by request with websearch_to_tsquery", "/prest-test/public/test5?name:websearch_to_tsquery=prest%20-rest", []string{`"name" @@ websearch_to_tsquery($1)`}, []string{`prest -rest`}, nil},
+		{"Where by request with plainto_tsquery", "/prest-test/public/test5?name:plainto_tsquery=prest%27)%20OR%201=1", []string{`"name" @@ plainto_tsquery($1)`}, []string{`prest') OR 1=1`}, nil},
+		{"Where by request with phraseto_tsquery", "/prest-test/public/test5?name:phraseto_tsquery=prest%20rest", []string{`"name" @@ phraseto_tsquery($1)`}, []string{`prest rest`}, nil},
 		{"Where by request with ltree left acendent", "/prest-test/public/test5?path='$ltreelanc.Top.*'", []string{`"path" @> $`}, []string{`'Top.*'`}, nil},
 		{"Where by request with ltree right descendent", "/prest-test/public/test5?path='$ltreerdesc.Top.*'", []string{`"path" <@ $`}, []string{`'Top.*'`}, nil},
 		{"Where by request with ltree match lquery", "/prest-test/public/test5?path='$ltreematch.Top.*'", []string{`"path" ~ $`}, []string{`'Top.*'`}, nil},
@@ -225,6 +229,148 @@ func TestWhereByRequest(t *testing.T) {
 	}
 }
 
+func TestWhereByRequestJSONB(t *testing.T) {
+	var testCases = []struct {
+		description string
+		url         string
+		where       string
+		values      []interface{}
+		err         error
+	}{
+		{"Text value", "/prest-test/public/test?data->>description:jsonb=$eq.prest", `"data"->>'description' = $1`, []interface{}{"prest"}, nil},
+		{"Nested path", "/prest-test/public/test?data->payload->>type:jsonb=$eq.click", `"data"->'payload'->>'type' = $1`, []interface{}{"click"}, nil},
+		{"Array index", "/prest-test/public/test?data->items->0->>id:jsonb=$gt.10", `"data"->'items'->0->>'id' > $1`, []interface{}{"10"}, nil},
+		{"Table column", "/prest-test/public/test?t.data->payload:jsonb=$eq.%7B%7D", `"t"."data"->'payload' = $1`, []interface{}{"{}"}, nil},
+		{"Contains", `/prest-test/public/test?data:jsonb=$contains.{"type":"click"}`, `"data" @> $1`, []interface{}{`{"type":"click"}`}, nil},
+		{"Contained by", `/prest-test/public/test?data->payload:jsonb=$containedby.{"a":1,"b":2}`, `"data"->'payload' <@ $1`, []interface{}{`{"a":1,"b":2}`}, nil},
+		{"Has key", "/prest-test/public/test?data:jsonb=$haskey.type", `"data" ? $1`, []interface{}{"type"}, nil},
+		{"Has keys", "/prest-test/public/test?data:jsonb=$haskeys.type,payload", `"data" ?& $1`, []interface{}{`{"type","payload"}`}, nil},
+		{"Has any key", "/prest-test/public/test?data:jsonb=$hasanykey.type,payload", `"data" ?| $1`, []interface{}{`{"type","payload"}`}, nil},
+		{"Jsonpath", `/prest-test/public/test?data:jsonb=$jsonpath.$.items[*] ? (@.price > 10)`, `"data" @? $1`, []interface{}{`$.items[*] ? (@.price > 10)`}, nil},
+		{"Value with operator like text", `/prest-test/public/test?data:jsonb=$contains.{"currency":"$usd."}`, `"data" @> $1`, []interface{}{`{"currency":"$usd."}`}, nil},
+		{"Invalid path key", "/prest-test/public/test?data->pay'load:jsonb=$eq.1", "", nil, ErrInvalidIdentifier},
+		{"Invalid column", "/prest-test/public/test?0data->payload:jsonb=$eq.1", "", nil, ErrInvalidIdentifier},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			req, err := http.NewRequest("GET", tc.url, nil)
+			require.Nil(t, err)
+
+			where, values, err := config.PrestConf.Adapter.WhereByRequest(req, 1)
+			require.ErrorIs(t, err, tc.err)
+			require.Equal(t, tc.where, where)
+			require.Equal(t, tc.values, values)
+		})
+	}
+}
+
+func TestWhereByRequestArrayRange(t *testing.T) {
+	var testCases = []struct {
+		description string
+		url         string
+		where       string
+		values      []interface{}
+		err         error
+	}{
+		{"Array overlap", "/prest-test/public/test?tags=$overlap.red,blue", `"tags" && $1`, []interface{}{`{"red","blue"}`}, nil},
+		{"Array contains", "/prest-test/public/test?tags=$arraycontains.red", `"tags" @> $1`, []interface{}{`{"red"}`}, nil},
+		{"Range contains range", "/prest-test/public/test?period=$rangecontains.[2024-01-01,2024-01-02)", `"period" @> $1`, []interface{}{"[2024-01-01,2024-01-02)"}, nil},
+		{"Range contains element", "/prest-test/public/test?period=$rangecontains.2024-01-01%2010:00", `"period" @> $1`, []interface{}{"[2024-01-01 10:00,2024-01-01 10:00]"}, nil},
+		{"Range overlap", "/prest-test/public/test?period=$rangeoverlap.(2024-01-01,2024-01-02]", `"period" && $1`, []interface{}{"(2024-01-01,2024-01-02]"}, nil},
+		{"Range adjacent", "/prest-test/public/test?period=$rangeadjacent.[1,5)", `"period" -|- $1`, []interface{}{"[1,5)"}, nil},
+		{"Range in filter group", `/prest-test/public/test?_or=(period.$rangeoverlap."[1,5)",tags.$overlap.(a,b))`, `("period" && $1 OR "tags" && $2)`, []interface{}{"[1,5)", `{"a","b"}`}, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			req, err := http.NewRequest("GET", tc.url, nil)
+			require.Nil(t, err)
+
+			where, values, err := config.PrestConf.Adapter.WhereByRequest(req, 1)
+			require.ErrorIs(t, err, tc.err)
+			require.Equal(t, tc.where, where)
+			require.Equal(t, tc.values, values)
+		})
+	}
+}
+
+func TestTextSearchByRequest(t *testing.T) {
+	var testCases = []struct {
+		description string
+		url         string
+		pid         int
+		rank        string
+		headlines   []string
+		values      []interface{}
+		err         error
+	}{
+		{"Without rank or headline", "/prest-test/public/test5?name:tsquery=prest", 1, "", nil, nil, nil},
+		{
+			"Rank",
+			"/prest-test/public/test5?name:websearch_to_tsquery=prest&_rank=name",
+			2,
+			`ts_rank(to_tsvector("name"), websearch_to_tsquery($2)) DESC`,
+			nil,
+			[]interface{}{"prest"},
+			nil,
+		},
+		{
+			"Rank with language",
+			"/prest-test/public/test5?name$english:tsquery=prest&_rank=name",
+			1,
+			`ts_rank(to_tsvector($1::regconfig, "name"), to_tsquery($1::regconfig, $2)) DESC`,
+			nil,
+			[]interface{}{"english", "prest"},
+			nil,
+		},
+		{
+			"Rank tsvector column",
+			"/prest-test/public/test5?document:plainto_tsquery=prest&_rank=document:tsvector",
+			1,
+			`ts_rank("document", plainto_tsquery($1)) DESC`,
+			nil,
+			[]interface{}{"prest"},
+			nil,
+		},
+		{
+			"Rank and headlines",
+			"/prest-test/public/test5?document$english:tsquery=prest&_rank=document:tsvector&_headline=body:document",
+			1,
+			`ts_rank("document", to_tsquery($1::regconfig, $2)) DESC`,
+			[]string{`ts_headline($3::regconfig, "body", to_tsquery($3::regconfig, $4)) AS "body_headline"`},
+			[]interface{}{"english", "prest", "english", "prest"},
+			nil,
+		},
+		{
+			"Headline",
+			"/prest-test/public/test5?name:tsquery=prest&_headline=name",
+			1,
+			"",
+			[]string{`ts_headline("name", to_tsquery($1)) AS "name_headline"`},
+			[]interface{}{"prest"},
+			nil,
+		},
+		{"Rank without search", "/prest-test/public/test5?name=prest&_rank=name", 1, "", nil, nil, ErrTextSearchNotFound},
+		{"Headline without search", "/prest-test/public/test5?name:tsquery=prest&_headline=body", 1, "", nil, nil, ErrTextSearchNotFound},
+		{"Headline invalid field", "/prest-test/public/test5?name:tsquery=prest&_headline=0body:name", 1, "", nil, nil, ErrInvalidIdentifier},
+		{"Invalid search field", "/prest-test/public/test5?0name:tsquery=prest&_rank=name", 1, "", nil, nil, ErrInvalidIdentifier},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			req, err := http.NewRequest("GET", tc.url, nil)
+			require.Nil(t, err)
+
+			rank, headlines, values, err := config.PrestConf.Adapter.TextSearchByRequest(req, tc.pid)
+			require.ErrorIs(t, err, tc.err)
+			require.Equal(t, tc.rank, rank)
+			require.Equal(t, tc.headlines, headlines)
+			require.Equal(t, tc.values, values)
+		})
+	}
+}
+
 func TestInvalidWhereByRequest(t *testing.T) {
 	var testCases = []struct {
 		description string
@@ -257,6 +403,46 @@ func TestInvalidWhereByRequest(t *testing.T) {
 	}
 }
 
+func TestWhereByRequestFilterGroups(t *testing.T) {
+	var testCases = []struct {
+		description    string
+		url            string
+		pid            int
+		expectedSQL    string
+		expectedValues []interface{}
+		err            error
+	}{
+		{"Where by request with or group", "/prest-test/public/test5?_or=(name.$eq.prest,age.$gte.3)", 1, `("name" = $1 OR "age" >= $2)`, []interface{}{"prest", "3"}, nil},
+		{"Where by request with and group", "/prest-test/public/test5?_and=(name.$eq.prest,age.$null)", 1, `("name" = $1 AND "age" IS NULL)`, []interface{}{"prest"}, nil},
+		{"Where by request with not group", "/prest-test/public/test5?_not=(name.$eq.prest)", 1, `NOT ("name" = $1)`, []interface{}{"prest"}, nil},
+		{"Where by request with nested groups", "/prest-test/public/test5?_or=(name.$eq.prest,and(age.$gte.3,c.celphone.$notnull),not(id.$in.(1,2)))", 1, `("name" = $1 OR ("age" >= $2 AND "c"."celphone" IS NOT NULL) OR NOT ("id" IN ($3,$4)))`, []interface{}{"prest", "3", "1", "2"}, nil},
+		{"Where by request with group and initial placeholder", "/prest-test/public/test5?_or=(name.$eq.prest,age.$gte.3)", 4, `("name" = $4 OR "age" >= $5)`, []interface{}{"prest", "3"}, nil},
+		{"Where by request with quoted group value", `/prest-test/public/test5?_or=(name.$eq."prest,(tester)",name.$like.%25val%25)`, 1, `("name" = $1 OR "name" LIKE $2)`, []interface{}{"prest,(tester)", "%val%"}, nil},
+		{"Where by request with unbalanced group", "/prest-test/public/test5?_or=(name.$eq.prest", 1, "", nil, ErrInvalidFilterGroup},
+		{"Where by request with empty group item", "/prest-test/public/test5?_or=(name.$eq.prest,,age.$eq.1)", 1, "", nil, ErrInvalidFilterGroup},
+		{"Where by request with group item without operator", "/prest-test/public/test5?_or=(name)", 1, "", nil, ErrInvalidFilterGroup},
+		{"Where by request with group invalid field", "/prest-test/public/test5?_or=(0name.$eq.prest)", 1, "", nil, ErrInvalidIdentifier},
+		{"Where by request with group invalid operator", "/prest-test/public/test5?_or=(name.$at.prest)", 1, "", nil, ErrInvalidOperator},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		req, err := http.NewRequest("GET", tc.url, nil)
+		require.NoError(t, err)
+
+		where, values, err := config.PrestConf.Adapter.WhereByRequest(req, tc.pid)
+		if tc.err != nil {
+			require.ErrorIs(t, err, tc.err)
+			require.Empty(t, where)
+			require.Nil(t, values)
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, tc.expectedSQL, where)
+		require.Equal(t, tc.expectedValues, values)
+	}
+}
+
 func TestReturningByRequest(t *testing.T) {
 	var testCases = []struct {
 		description string
@@ -293,21 +479,27 @@ func TestGroupByClause(t *testing.T) {
 		description string
 		url         string
 		expectedSQL string
+		values      []interface{}
 		emptyCase   bool
 	}{
-		{"Group by clause with one field", "/prest-test/public/test5?_groupby=celphone", `GROUP BY "celphone"`, false},
-		{"Group by clause with two fields", "/prest-test/public/test5?_groupby=celphone,name", `GROUP BY "celphone","name"`, false},
-		{"Group by clause with two fields", "/prest-test/public/test5?_groupby=c.celphone,c.name", `GROUP BY "c"."celphone","c"."name"`, false},
-		{"Group by clause without fields", "/prest-test/public/test5?_groupby=", "", true},
+		{"Group by clause with one field", "/prest-test/public/test5?_groupby=celphone", `GROUP BY "celphone"`, nil, false},
+		{"Group by clause with two fields", "/prest-test/public/test5?_groupby=celphone,name", `GROUP BY "celphone","name"`, nil, false},
+		{"Group by clause with two fields", "/prest-test/public/test5?_groupby=c.celphone,c.name", `GROUP BY "c"."celphone","c"."name"`, nil, false},
+		{"Group by clause without fields", "/prest-test/public/test5?_groupby=", "", nil, true},
 
 		// having tests
-		{"Group by clause with having clause", "/prest-test/public/test5?_groupby=celphone->>having:sum:salary:$gt:500", `GROUP BY "celphone" HAVING SUM("salary") > 500`, false},
-		{"Group by clause with having clause", "/prest-test/public/test5?_groupby=c.celphone->>having:sum:salary:$gt:500", `GROUP BY "c"."celphone" HAVING SUM("salary") > 500`, false},
+		{"Group by clause with having clause", "/prest-test/public/test5?_groupby=celphone->>having:sum:salary:$gt:500", `GROUP BY "celphone" HAVING SUM("salary") > $1`, []interface{}{"500"}, false},
+		{"Group by clause with having clause", "/prest-test/public/test5?_groupby=c.celphone->>having:sum:salary:$gt:500", `GROUP BY "c"."celphone" HAVING SUM("salary") > $1`, []interface{}{"500"}, false},
+		{"Group by clause with having conditions", "/prest-test/public/test5?_groupby=celphone->>having:sum:salary:$gt:500,count:*:$gte:2", `GROUP BY "celphone" HAVING SUM("salary") > $1 AND COUNT(*) >= $2`, []interface{}{"500", "2"}, false},
+		{"Group by clause with having or group", "/prest-test/public/test5?_groupby=celphone->>having:or(max:age:$lt:18,countdistinct:name:$eq:1)", `GROUP BY "celphone" HAVING (MAX("age") < $1 OR COUNT(DISTINCT "name") = $2)`, []interface{}{"18", "1"}, false},
+		{"Group by clause with having and nested groups", "/prest-test/public/test5?_groupby=celphone->>having:bool_or:active:$true,not(avg:salary:$in:(1,2))", `GROUP BY "celphone" HAVING BOOL_OR("active") IS TRUE AND NOT (AVG("salary") IN ($1,$2))`, []interface{}{"1", "2"}, false},
+		{"Group by clause with having string_agg", "/prest-test/public/test5?_groupby=celphone->>having:string_agg(|):name:$eq:a|b", `GROUP BY "celphone" HAVING STRING_AGG("name", $1) = $2`, []interface{}{"|", "a|b"}, false},
 
 		// having errors, but continue with group by
-		{"Group by clause with wrong having clause (insufficient params)", "/prest-test/public/test5?_groupby=celphone->>having:sum:salary", `GROUP BY "celphone"`, false},
-		{"Group by clause with wrong having clause (wrong query operator)", "/prest-test/public/test5?_groupby=celphone->>having:sum:salary:$at:500", `GROUP BY "celphone"`, false},
-		{"Group by clause with wrong having clause (wrong group func)", "/prest-test/public/test5?_groupby=celphone->>having:sun:salary:$gt:500", `GROUP BY "celphone"`, false},
+		{"Group by clause with wrong having clause (insufficient params)", "/prest-test/public/test5?_groupby=celphone->>having:sum:salary", `GROUP BY "celphone"`, nil, false},
+		{"Group by clause with wrong having clause (wrong query operator)", "/prest-test/public/test5?_groupby=celphone->>having:sum:salary:$at:500", `GROUP BY "celphone"`, nil, false},
+		{"Group by clause with wrong having clause (wrong group func)", "/prest-test/public/test5?_groupby=celphone->>having:sun:salary:$gt:500", `GROUP BY "celphone"`, nil, false},
+		{"Group by clause with wrong having clause (unbalanced group)", "/prest-test/public/test5?_groupby=celphone->>having:or(sum:salary:$gt:500", `GROUP BY "celphone"`, nil, false},
 	}
 
 	for _, tc := range testCases {
@@ -317,7 +509,7 @@ func TestGroupByClause(t *testing.T) {
 			t.Errorf("expected no errors in http request, got %v", err)
 		}
 
-		groupBySQL := config.PrestConf.Adapter.GroupByClause(req)
+		groupBySQL, values := config.PrestConf.Adapter.GroupByClause(req, 1)
 
 		if !tc.emptyCase && groupBySQL == "" {
 			t.Error("expected groupBySQL, got empty string")
@@ -330,6 +522,8 @@ func TestGroupByClause(t *testing.T) {
 		if groupBySQL != tc.expectedSQL {
 			t.Errorf("expected %s, got %s", tc.expectedSQL, groupBySQL)
 		}
+
+		require.Equal(t, tc.values, values)
 	}
 }
 
@@ -464,6 +658,143 @@ func TestPaginateIfPossible(t *testing.T) {
 	}
 }
 
+func TestPaginateIfPossibleKeyset(t *testing.T) {
+	var testCases = []struct {
+		description string
+		url         string
+		expected    string
+		err         error
+	}{
+		{"First page", "/prest-test/public/test?_order=id&_after=", "LIMIT 11", nil},
+		{"Page size", "/prest-test/public/test?_order=id&_after=WzFd&_page_size=5", "LIMIT 6", nil},
+		{"Backwards", "/prest-test/public/test?_order=id&_before=WzFd&_page_size=5", "LIMIT 6", nil},
+		{"With _page", "/prest-test/public/test?_order=id&_after=WzFd&_page=2", "", ErrKeysetWithPage},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			req, err := http.NewRequest("GET", tc.url, nil)
+			require.Nil(t, err)
+
+			sql, err := config.PrestConf.Adapter.PaginateIfPossible(req)
+			require.ErrorIs(t, err, tc.err)
+			require.Equal(t, tc.expected, sql)
+		})
+	}
+}
+
+func TestKeysetByRequest(t *testing.T) {
+	var testCases = []struct {
+		description string
+		url         string
+		pid         int
+		expected    string
+		values      []interface{}
+		err         error
+	}{
+		{"Without cursor", "/prest-test/public/test?_order=id", 1, "", nil, nil},
+		{"First page", "/prest-test/public/test?_order=id&_after=", 1, "", nil, nil},
+		{
+			"After",
+			"/prest-test/public/test?_order=id&_after=WzEwXQ",
+			1,
+			`(("id" > $1))`,
+			[]interface{}{"10"},
+			nil,
+		},
+		{
+			"After with many fields and placeholder offset",
+			"/prest-test/public/test?_order=-name,id&_after=WyJwcmVzdCIsMTBd",
+			3,
+			`(("name" < $3) OR ("name" = $3 AND "id" > $4))`,
+			[]interface{}{"prest", "10"},
+			nil,
+		},
+		{
+			"Before",
+			"/prest-test/public/test?_order=-name,id&_before=WyJwcmVzdCIsMTBd",
+			1,
+			`(("name" > $1) OR ("name" = $1 AND "id" < $2))`,
+			[]interface{}{"prest", "10"},
+			nil,
+		},
+		{"Without order", "/prest-test/public/test?_after=WzEwXQ", 1, "", nil, ErrKeysetWithoutOrder},
+		{"Both directions", "/prest-test/public/test?_order=id&_after=WzEwXQ&_before=WzEwXQ", 1, "", nil, ErrKeysetBothDirections},
+		{"Invalid cursor", "/prest-test/public/test?_order=id&_after=prest", 1, "", nil, ErrInvalidCursor},
+		{"Cursor size", "/prest-test/public/test?_order=id,name&_after=WzEwXQ", 1, "", nil, ErrInvalidCursor},
+		{"Order expression", "/prest-test/public/test?_order=lower(name)&_after=WzEwXQ", 1, "", nil, ErrKeysetOrderExpression},
+		{"Nulls placement", "/prest-test/public/test?_order=name.nullslast&_after=", 1, "", nil, ErrKeysetOrderExpression},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			req, err := http.NewRequest("GET", tc.url, nil)
+			require.Nil(t, err)
+
+			keyset, values, err := config.PrestConf.Adapter.KeysetByRequest(req, tc.pid)
+			require.ErrorIs(t, err, tc.err)
+			require.Equal(t, tc.expected, keyset)
+			require.Equal(t, tc.values, values)
+		})
+	}
+}
+
+func TestKeysetPage(t *testing.T) {
+	body := `[{"id": 1, "name": "a"}, {"id": 2, "name": "b"}, {"id": 3, "name": "c"}]`
+	var testCases = []struct {
+		description string
+		url         string
+		body        string
+		page        string
+		next        string
+		prev        string
+	}{
+		{
+			"First page",
+			"/prest-test/public/test?_order=id&_after=&_page_size=2",
+			body,
+			`[{"id": 1, "name": "a"}, {"id": 2, "name": "b"}]`,
+			"WzJd",
+			"",
+		},
+		{
+			"Last page",
+			"/prest-test/public/test?_order=id&_after=WzBd&_page_size=3",
+			body,
+			`[{"id": 1, "name": "a"}, {"id": 2, "name": "b"}, {"id": 3, "name": "c"}]`,
+			"",
+			"WzFd",
+		},
+		{
+			"Backwards",
+			"/prest-test/public/test?_order=id&_before=WzRd&_page_size=2",
+			`[{"id": 3, "name": "c"}, {"id": 2, "name": "b"}, {"id": 1, "name": "a"}]`,
+			`[{"id": 2, "name": "b"}, {"id": 3, "name": "c"}]`,
+			"WzNd",
+			"WzJd",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			req, err := http.NewRequest("GET", tc.url, nil)
+			require.Nil(t, err)
+
+			page, next, prev, err := config.PrestConf.Adapter.KeysetPage(req, []byte(tc.body))
+			require.Nil(t, err)
+			require.Equal(t, tc.page, string(page))
+			require.Equal(t, tc.next, next)
+			require.Equal(t, tc.prev, prev)
+		})
+	}
+
+	t.Log("Order column not selected")
+	req, err := http.NewRequest("GET", "/prest-test/public/test?_order=number&_after=&_page_size=2", nil)
+	require.Nil(t, err)
+	_, _, _, err = config.PrestConf.Adapter.KeysetPage(req, []byte(body))
+	require.ErrorIs(t, err, ErrInvalidCursor)
+}
+
 func TestInvalidPaginateIfPossible(t *testing.T) {
 	var testCases = []struct {
 		description string
@@ -814,6 +1145,66 @@ func TestJoinByRequest(t *testing.T) {
 	}
 }
 
+func TestJoinByRequestMultiple(t *testing.T) {
+	var testCases = []struct {
+		description string
+		url         string
+		expected    []string
+		err         error
+	}{
+		{
+			"Join with alias",
+			"/prest-test/public/test?_join=left:public.test2:t2:t2.name:$eq:test.name",
+			[]string{` LEFT JOIN "public"."test2" AS "t2" ON "t2"."name" = "test"."name" `},
+			nil,
+		},
+		{
+			"Chained joins",
+			"/prest-test/public/test?_join=inner:test2:t2:t2.name:$eq:test.name&_join=outer:test3:t3:t3.id:$eq:t2.id&_join=right:test4:test4.name:$eq:t3.name",
+			[]string{
+				` INNER JOIN "test2" AS "t2" ON "t2"."name" = "test"."name" `,
+				` FULL OUTER JOIN "test3" AS "t3" ON "t3"."id" = "t2"."id" `,
+				` RIGHT JOIN "test4" ON "test4"."name" = "t3"."name" `,
+			},
+			nil,
+		},
+		{"Invalid join type", "/prest-test/public/test?_join=natural:test2:test2.name:$eq:test.name", nil, ErrInvalidJoinClause},
+		{"Invalid alias", "/prest-test/public/test?_join=inner:test2:public.t2:t2.name:$eq:test.name", nil, ErrInvalidIdentifier},
+		{"Invalid number of arguments", "/prest-test/public/test?_join=inner:test2:test2.name:$eq:test.name&_join=inner:test3", nil, ErrJoinInvalidNumberOfArgs},
+		{"Invalid field", "/prest-test/public/test?_join=inner:test2:t2:name:$eq:test.name", nil, ErrInvalidJoinClause},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			req, err := http.NewRequest("GET", tc.url, nil)
+			require.Nil(t, err)
+
+			join, err := config.PrestConf.Adapter.JoinByRequest(req)
+			require.ErrorIs(t, err, tc.err)
+			require.Equal(t, tc.expected, join)
+		})
+	}
+
+	t.Run("Join without permission", func(t *testing.T) {
+		restrict, tables := config.PrestConf.AccessConf.Restrict, config.PrestConf.AccessConf.Tables
+		defer func() {
+			config.PrestConf.AccessConf.Restrict, config.PrestConf.AccessConf.Tables = restrict, tables
+		}()
+		config.PrestConf.AccessConf.Restrict = true
+		config.PrestConf.AccessConf.Tables = []config.TablesConf{
+			{Name: "test", Permissions: []string{"read"}},
+			{Name: "test2", Permissions: []string{"write"}},
+		}
+
+		req, err := http.NewRequest("GET", "/prest-test/public/test?_join=inner:public.test2:test2.name:$eq:test.name", nil)
+		require.Nil(t, err)
+
+		join, err := config.PrestConf.Adapter.JoinByRequest(req)
+		require.ErrorIs(t, err, ErrJoinNotPermitted)
+		require.Nil(t, join)
+	})
+}
+
 func TestCountFields(t *testing.T) {
 	var testCases = []struct {
 		description string
@@ -940,6 +1331,17 @@ func TestGetQueryOperator(t *testing.T) {
 		{"$ltreerdesc", "<@"},
 		{"$ltreematch", "~"},
 		{"$ltreematchtxt", "@"},
+		{"$contains", "@>"},
+		{"$containedby", "<@"},
+		{"$haskey", "?"},
+		{"$haskeys", "?&"},
+		{"$hasanykey", "?|"},
+		{"$jsonpath", "@?"},
+		{"$overlap", "&&"},
+		{"$arraycontains", "@>"},
+		{"$rangecontains", "@>"},
+		{"$rangeoverlap", "&&"},
+		{"$rangeadjacent", "-|-"},
 	}
 
 	for _, tc := range testCases {
@@ -1021,6 +1423,50 @@ func TestOrderByRequest(t *testing.T) {
 	if order != "" {
 		t.Errorf("expected order empty, got: %s", order)
 	}
+
+	t.Log("Query ORDER BY reversed by _before")
+	r, err = http.NewRequest("GET", "/prest-test/public/test?_order=name,-number&_before=", nil)
+	if err != nil {
+		t.Errorf("expected no errors on NewRequest, got: %v", err)
+	}
+
+	order, err = config.PrestConf.Adapter.OrderByRequest(r)
+	if err != nil {
+		t.Errorf("expected no errors on OrderByRequest, got: %v", err)
+	}
+	if order != ` ORDER BY "name" DESC, "number"` {
+		t.Errorf("expected reversed order, got: %s", order)
+	}
+}
+
+func TestOrderByRequestExpressions(t *testing.T) {
+	var testCases = []struct {
+		description string
+		url         string
+		expected    string
+		err         error
+	}{
+		{"Nulls last", "/prest-test/public/test?_order=-updated_at.nullslast", ` ORDER BY "updated_at" DESC NULLS LAST`, nil},
+		{"Nulls first", "/prest-test/public/test?_order=c.name.NullsFirst", ` ORDER BY "c"."name" NULLS FIRST`, nil},
+		{"JSON field", "/prest-test/public/test?_order=data->>priority,-data->tags->0", ` ORDER BY "data"->>'priority', "data"->'tags'->0 DESC`, nil},
+		{"Function", "/prest-test/public/test?_order=lower(name),-length(data->>title).nullsfirst", ` ORDER BY lower("name"), length("data"->>'title') DESC NULLS FIRST`, nil},
+		{"Nested functions", "/prest-test/public/test?_order=upper(trim(c.name))", ` ORDER BY upper(trim("c"."name"))`, nil},
+		{"Function out of the allow-list", "/prest-test/public/test?_order=pg_sleep(10)", "", ErrInvalidOrderFunction},
+		{"Function with many arguments", "/prest-test/public/test?_order=lower(name,number)", "", ErrInvalidIdentifier},
+		{"Invalid JSON field", "/prest-test/public/test?_order=data->>0name", "", ErrInvalidIdentifier},
+		{"Unbalanced function", "/prest-test/public/test?_order=lower(name", "", ErrInvalidIdentifier},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			req, err := http.NewRequest("GET", tc.url, nil)
+			require.Nil(t, err)
+
+			order, err := config.PrestConf.Adapter.OrderByRequest(req)
+			require.ErrorIs(t, err, tc.err)
+			require.Equal(t, tc.expected, order)
+		})
+	}
 }
 
 func TestTablePermissions(t *testing.T) {
@@ -1091,6 +1537,7 @@ func TestSelectFields(t *testing.T) {
 		{"One field with alias", []string{"c.test"}, `SELECT "c"."test" FROM`},
 		{"More field", []string{"test", "test02"}, `SELECT "test","test02" FROM`},
 		{"Aggregation Fields", []string{"max:age"}, `SELECT MAX("age") FROM`},
+		{"Normalized aggregation Fields", []string{"age", `COUNT(DISTINCT "name") AS "names"`}, `SELECT "age",COUNT(DISTINCT "name") AS "names" FROM`},
 	}
 	var testErrorCases = []struct {
 		description string
@@ -1103,7 +1550,7 @@ func TestSelectFields(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Log(tc.description)
-		sql, err := config.PrestConf.Adapter.SelectFields(tc.fields)
+		sql, _, err := config.PrestConf.Adapter.SelectFields(tc.fields, 1)
 		if err != nil {
 			t.Errorf("expected no errors, but got: %v", err)
 		}
@@ -1115,7 +1562,7 @@ func TestSelectFields(t *testing.T) {
 
 	for _, tc := range testErrorCases {
 		t.Log(tc.description)
-		sql, err := config.PrestConf.Adapter.SelectFields(tc.fields)
+		sql, _, err := config.PrestConf.Adapter.SelectFields(tc.fields, 1)
 		if err == nil {
 			t.Errorf("expected errors, but got: %v", err)
 		}
@@ -1124,6 +1571,12 @@ func TestSelectFields(t *testing.T) {
 			t.Errorf("expected '%s', got: '%s'", tc.expectedSQL, sql)
 		}
 	}
+
+	t.Log("Delimiters of string_agg bound as placeholders")
+	sql, values, err := config.PrestConf.Adapter.SelectFields([]string{"name", "string_agg(|):name:names", "string_agg:email"}, 3)
+	require.NoError(t, err)
+	require.Equal(t, `SELECT "name",STRING_AGG("name", $3) AS "names",STRING_AGG("email", $4) FROM`, sql)
+	require.Equal(t, []interface{}{"|", ","}, values)
 }
 
 func TestColumnsByRequest(t *testing.T) {
@@ -1139,6 +1592,8 @@ func TestColumnsByRequest(t *testing.T) {
 		{"Select with more columns", "/prest-test/public/test5?_select=celphone,battery", "celphone,battery"},
 		{"Select with more columns", "/prest-test/public/test5?_select=age,sum:salary&_groupby=age", `age,SUM("salary")`},
 		{"Select with one aggregate function without group by", "/prest-test/public/test5?_select=sum:salary", `sum:salary`},
+		{"Select with string_agg kept for its delimiter to be bound", "/prest-test/public/test5?_select=age,string_agg(|):name&_groupby=age", `age,string_agg(|):name`},
+		{"Select with aggregate functions and time bucket", "/prest-test/public/test5?_select=avg:salary,count:*&_bucket=created_at:1d", `AVG("salary"),COUNT(*)`},
 	}
 	for _, tc := range testCases {
 		r, err := http.NewRequest("GET", tc.url, nil)
@@ -1202,10 +1657,33 @@ func TestNormalizeGroupFunction(t *testing.T) {
 		{"Normalize MIN Function renamed", "min:age:colname", `MIN("age") AS "colname"`},
 		{"Normalize STDDEV Function renamed", "stddev:age:colname", `STDDEV("age") AS "colname"`},
 		{"Normalize VARIANCE Function renamed", "variance:age:colname", `VARIANCE("age") AS "colname"`},
+		{"Normalize COUNT Function", "count:*", `COUNT(*)`},
+		{"Normalize COUNT Function of a field", "count:c.age:total", `COUNT("c"."age") AS "total"`},
+		{"Normalize COUNT DISTINCT Function", "countdistinct:age", `COUNT(DISTINCT "age")`},
+		{"Normalize ARRAY_AGG Function", "array_agg:name:names", `ARRAY_AGG("name") AS "names"`},
+		{"Normalize STRING_AGG Function", "string_agg:name", `STRING_AGG("name", $1)`},
+		{"Normalize STRING_AGG Function with delimiter", "string_agg(|):name", `STRING_AGG("name", $1)`},
+		{"Normalize BOOL_AND Function", "bool_and:active", `BOOL_AND("active")`},
+		{"Normalize BOOL_OR Function", "bool_or:active", `BOOL_OR("active")`},
+		{"Normalize PERCENTILE_CONT Function", "percentile_cont(0.5):salary:median", `PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY "salary") AS "median"`},
+	}
+	var testErrorCases = []struct {
+		description string
+		urlValue    string
+		err         error
+	}{
+		{"Unknown function", "sun:age", ErrInvalidGroupFn},
+		{"Missing field", "sum", ErrInvalidGroupFn},
+		{"Argument of a function without arguments", "sum(1):age", ErrInvalidGroupFn},
+		{"COUNT DISTINCT of all fields", "countdistinct:*", ErrInvalidGroupFn},
+		{"PERCENTILE_CONT without fraction", "percentile_cont:salary", ErrInvalidGroupFn},
+		{"PERCENTILE_CONT out of range", "percentile_cont(2):salary", ErrInvalidGroupFn},
+		{"Invalid field", "sum:0age", ErrInvalidIdentifier},
+		{"Invalid alias", "sum:age:0total", ErrInvalidIdentifier},
 	}
 
 	for _, tc := range testCases {
-		partialSQL, err := NormalizeGroupFunction(tc.urlValue)
+		partialSQL, _, err := NormalizeGroupFunction(tc.urlValue, 1)
 		if err != nil {
 			t.Errorf("This function should not return error: %s", tc.description)
 		}
@@ -1214,6 +1692,11 @@ func TestNormalizeGroupFunction(t *testing.T) {
 			t.Errorf("expected: %s, got: %s", tc.expectedSQL, partialSQL)
 		}
 	}
+
+	for _, tc := range testErrorCases {
+		_, _, err := NormalizeGroupFunction(tc.urlValue, 1)
+		require.ErrorIs(t, err, tc.err, tc.description)
+	}
 }
 
 func TestCacheQuery(t *testing.T) {
@@ -1688,6 +2171,18 @@ func TestPostgres_FieldsPermissions(t *testing.T) {
 			wantErr:    false,
 			wantFields: []string{`MAX("age")`},
 		},
+		{
+			name: "select with string_agg of an allowed field returns it as sent",
+			args: args{
+				url:    "/table_field_permission?_groupby=age&_select=string_agg(|):name",
+				table:  "test_field_permission",
+				op:     "write",
+				fields: []string{"name", "age"},
+			},
+			restrict:   true,
+			wantErr:    false,
+			wantFields: []string{"string_agg(|):name"},
+		},
 	}
 	for _, tt := range tests {
 		config.PrestConf.AccessConf.Restrict = tt.restrict
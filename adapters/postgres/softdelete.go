@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/prest/prest/config"
+	permissions "github.com/prest/prest/middlewares/statements"
+)
+
+// softDeleteColumn returns the soft_delete column of a table, empty when
+// its rows are deleted
+func softDeleteColumn(table string) (column string) {
+	for _, t := range config.PrestConf.AccessConf.Tables {
+		if t.Name == table && t.SoftDelete != "" {
+			return t.SoftDelete
+		}
+	}
+	return
+}
+
+// SoftDeleteWhere returns the condition that excludes the soft deleted
+// rows of a table, empty when the table has no soft_delete column
+func (adapter *Postgres) SoftDeleteWhere(table string) (whereSyntax string) {
+	column := softDeleteColumn(table)
+	if column == "" {
+		return
+	}
+	return fmt.Sprintf("%s.%s IS NULL", pq.QuoteIdentifier(table), pq.QuoteIdentifier(column))
+}
+
+// relatedSoftDeleteWhere returns the condition that excludes the soft
+// deleted rows of a table read by `_join` or `_expand` as qualifier. It is
+// empty when the table has no soft_delete column or its deleted rows are
+// requested with `_with_deleted=true` and the read_deleted permission
+func (adapter *Postgres) relatedSoftDeleteWhere(r *http.Request, qualifier, table string) (whereSyntax string) {
+	column := softDeleteColumn(table)
+	if column == "" {
+		return
+	}
+	if r.URL.Query().Get("_with_deleted") == "true" && adapter.TablePermissions(table, permissions.READDELETED) {
+		return
+	}
+	return fmt.Sprintf("%s.%s IS NULL", qualifier, pq.QuoteIdentifier(column))
+}
+
+// SoftDeleteSQL returns the update that marks the rows of a table as
+// deleted, empty when the table has no soft_delete column
+func (adapter *Postgres) SoftDeleteSQL(database, schema, table string) (sql string) {
+	column := softDeleteColumn(table)
+	if column == "" {
+		return
+	}
+	return adapter.UpdateSQL(database, schema, table, fmt.Sprintf("%s = now()", pq.QuoteIdentifier(column)))
+}
+
+// WithDeletedByRequest returns true when the soft deleted rows are
+// requested with `_with_deleted=true`, which requires the read_deleted
+// permission on the table
+func (adapter *Postgres) WithDeletedByRequest(r *http.Request, table string) (withDeleted bool, err error) {
+	if r.URL.Query().Get("_with_deleted") != "true" {
+		return
+	}
+	if !adapter.TablePermissions(table, permissions.READDELETED) {
+		err = errors.Wrapf(ErrWithDeletedNotPermitted, "%s", table)
+		return
+	}
+	return true, nil
+}
@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prest/prest/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSoftDelete(t *testing.T) {
+	restrict, tables := config.PrestConf.AccessConf.Restrict, config.PrestConf.AccessConf.Tables
+	defer func() {
+		config.PrestConf.AccessConf.Restrict, config.PrestConf.AccessConf.Tables = restrict, tables
+	}()
+	config.PrestConf.AccessConf.Tables = []config.TablesConf{
+		{Name: "test", Permissions: []string{"read", "delete", "read_deleted"}, SoftDelete: "deleted_at"},
+		{Name: "test2", Permissions: []string{"read", "delete"}, SoftDelete: "removed"},
+		{Name: "test3", Permissions: []string{"read", "delete"}},
+	}
+	adapter := &Postgres{}
+
+	t.Run("SQL", func(t *testing.T) {
+		require.Equal(t, `"test"."deleted_at" IS NULL`, adapter.SoftDeleteWhere("test"))
+		require.Equal(t, `UPDATE "prest"."public"."test" SET "deleted_at" = now()`, adapter.SoftDeleteSQL("prest", "public", "test"))
+		require.Equal(t, "", adapter.SoftDeleteWhere("test3"))
+		require.Equal(t, "", adapter.SoftDeleteSQL("prest", "public", "test3"))
+	})
+
+	t.Run("Related deleted rows", func(t *testing.T) {
+		config.PrestConf.AccessConf.Restrict = true
+		r := httptest.NewRequest("GET", "/prest/public/test3?_join=inner:test2:test2.name:$eq:test3.name&_join=left:test:t:t.id:$eq:test3.id", nil)
+		join, err := adapter.JoinByRequest(r)
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			` INNER JOIN "test2" ON "test2"."name" = "test3"."name" AND "test2"."removed" IS NULL `,
+			` LEFT JOIN "test" AS "t" ON "t"."id" = "test3"."id" AND "t"."deleted_at" IS NULL `,
+		}, join)
+
+		fks := []foreignKey{{Schema: "public", Table: "test2", Columns: []string{"test3_id"}, ForeignSchema: "public", ForeignTable: "test3", ForeignColumns: []string{"id"}}}
+		notDeleted := adapter.relatedSoftDeleteWhere(r, expandAlias, "test2")
+		expr, err := expandSQL("public", "test3", expandItem{name: "test2", columns: []string{"*"}}, fks, notDeleted)
+		require.NoError(t, err)
+		require.Equal(t, `(SELECT COALESCE(jsonb_agg(_row), '[]'::jsonb) FROM (SELECT "_expand".* FROM "public"."test2" AS "_expand" WHERE "_expand"."test3_id" = "public"."test3"."id" AND "_expand"."removed" IS NULL) _row) AS "test2"`, expr)
+
+		r = httptest.NewRequest("GET", "/prest/public/test3?_with_deleted=true", nil)
+		require.Equal(t, "", adapter.relatedSoftDeleteWhere(r, expandAlias, "test"))
+		require.Equal(t, `"_expand"."removed" IS NULL`, adapter.relatedSoftDeleteWhere(r, expandAlias, "test2"))
+		require.Equal(t, "", adapter.relatedSoftDeleteWhere(r, expandAlias, "test3"))
+	})
+
+	var testCases = []struct {
+		description string
+		url         string
+		table       string
+		restrict    bool
+		withDeleted bool
+		err         error
+	}{
+		{"Deleted rows not requested", "/prest/public/test", "test", true, false, nil},
+		{"Deleted rows requested", "/prest/public/test?_with_deleted=true", "test", true, true, nil},
+		{"Deleted rows without permission", "/prest/public/test2?_with_deleted=true", "test2", true, false, ErrWithDeletedNotPermitted},
+		{"Deleted rows unrestricted", "/prest/public/test2?_with_deleted=true", "test2", false, true, nil},
+		{"Deleted rows not true", "/prest/public/test2?_with_deleted=false", "test2", true, false, nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			config.PrestConf.AccessConf.Restrict = tc.restrict
+			r := httptest.NewRequest("GET", tc.url, nil)
+			withDeleted, err := adapter.WithDeletedByRequest(r, tc.table)
+			require.ErrorIs(t, err, tc.err)
+			require.Equal(t, tc.withDeleted, withDeleted)
+		})
+	}
+}
@@ -130,7 +130,256 @@ FROM`
 	GroupBy = `GROUP BY %s`
 
 	// Having query
-	Having = `HAVING %s %s %s`
+	Having = `HAVING %s`
+
+	// TimescaleInstalled checks if the timescaledb extension is installed
+	TimescaleInstalled = `SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'timescaledb')`
+
+	// GapFill adds the empty buckets between the first and the last bucket
+	// of a time bucketed query, the series is selected last to replace the
+	// null bucket of the empty rows on the json object
+	GapFill = `WITH _bucket AS (%[1]s) SELECT _bucket.*, _series AS %[2]s FROM generate_series((SELECT min(%[2]s) FROM _bucket), (SELECT max(%[2]s) FROM _bucket), $%[3]d::interval) _series LEFT JOIN _bucket ON _bucket.%[2]s = _series ORDER BY _series`
+
+	// StreamRows returns each row of a query as a json object
+	StreamRows = `SELECT to_jsonb(s) FROM (%s) s`
+
+	// TotalCount counts the rows of a query
+	TotalCount = `SELECT COUNT(*) FROM (%s) _count`
+
+	// PlannedCount explains a query to get the planner estimate of its rows
+	PlannedCount = `EXPLAIN (FORMAT JSON) %s`
+
+	// TableCountEstimate returns the statistics of the number of rows of a
+	// table, -1 for views and tables that were never analyzed
+	TableCountEstimate = `
+SELECT
+	CASE WHEN c.relkind IN ('r', 'm', 'p') THEN c.reltuples::bigint ELSE -1 END
+FROM
+	pg_catalog.pg_class c
+INNER JOIN
+	pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+WHERE
+	n.nspname = $1 AND
+	c.relname = $2`
+
+	// ForeignKeys lists the foreign keys from and to a table
+	ForeignKeys = `
+SELECT
+	c.conname AS "name",
+	sn.nspname AS "schema",
+	st.relname AS "table",
+	ARRAY(
+		SELECT a.attname
+		FROM unnest(c.conkey) WITH ORDINALITY AS k(attnum, n)
+		JOIN pg_catalog.pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum
+		ORDER BY k.n
+	) AS "columns",
+	fn.nspname AS "foreign_schema",
+	ft.relname AS "foreign_table",
+	ARRAY(
+		SELECT a.attname
+		FROM unnest(c.confkey) WITH ORDINALITY AS k(attnum, n)
+		JOIN pg_catalog.pg_attribute a ON a.attrelid = c.confrelid AND a.attnum = k.attnum
+		ORDER BY k.n
+	) AS "foreign_columns"
+FROM
+	pg_catalog.pg_constraint c
+INNER JOIN
+	pg_catalog.pg_class st ON st.oid = c.conrelid
+INNER JOIN
+	pg_catalog.pg_namespace sn ON sn.oid = st.relnamespace
+INNER JOIN
+	pg_catalog.pg_class ft ON ft.oid = c.confrelid
+INNER JOIN
+	pg_catalog.pg_namespace fn ON fn.oid = ft.relnamespace
+WHERE
+	c.contype = 'f' AND
+	((sn.nspname = $1 AND st.relname = $2) OR (fn.nspname = $1 AND ft.relname = $2))
+ORDER BY
+	c.conname`
+
+	// PrimaryKey lists the columns of the primary key of a table in the
+	// order of the key
+	PrimaryKey = `
+SELECT
+	a.attname AS "column"
+FROM
+	pg_catalog.pg_index i
+INNER JOIN
+	pg_catalog.pg_class c ON c.oid = i.indrelid
+INNER JOIN
+	pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+CROSS JOIN
+	unnest(i.indkey::int2[]) WITH ORDINALITY AS k(attnum, ord)
+INNER JOIN
+	pg_catalog.pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = k.attnum
+WHERE
+	i.indisprimary AND
+	n.nspname = $1 AND
+	c.relname = $2
+ORDER BY
+	k.ord`
+
+	// TableDetail returns the document of a table: its columns, with their
+	// comments and enum values, constraints and indexes
+	TableDetail = `
+SELECT
+	jsonb_build_object(
+		'schema', n.nspname,
+		'table', c.relname,
+		'kind', CASE c.relkind
+			WHEN 'r' THEN 'table'
+			WHEN 'p' THEN 'partitioned table'
+			WHEN 'v' THEN 'view'
+			WHEN 'm' THEN 'materialized view'
+			WHEN 'f' THEN 'foreign table'
+		END,
+		'comment', obj_description(c.oid, 'pg_class'),
+		'columns', COALESCE((
+			SELECT
+				jsonb_agg(jsonb_build_object(
+					'name', a.attname,
+					'position', a.attnum,
+					'type', format_type(a.atttypid, a.atttypmod),
+					'nullable', NOT a.attnotnull,
+					'default', pg_get_expr(d.adbin, d.adrelid),
+					'identity', CASE a.attidentity WHEN 'a' THEN 'always' WHEN 'd' THEN 'by default' END,
+					'generated', a.attgenerated = 's',
+					'comment', col_description(c.oid, a.attnum),
+					'enum_values', (
+						SELECT jsonb_agg(e.enumlabel ORDER BY e.enumsortorder)
+						FROM pg_catalog.pg_enum e
+						WHERE e.enumtypid = CASE WHEN t.typcategory = 'A' THEN t.typelem ELSE t.oid END
+					)
+				) ORDER BY a.attnum)
+			FROM
+				pg_catalog.pg_attribute a
+			INNER JOIN
+				pg_catalog.pg_type t ON t.oid = a.atttypid
+			LEFT JOIN
+				pg_catalog.pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+			WHERE
+				a.attrelid = c.oid AND
+				a.attnum > 0 AND
+				NOT a.attisdropped
+		), '[]'),
+		'primary_key', (
+			SELECT jsonb_build_object('name', co.conname, 'columns', (
+				SELECT jsonb_agg(a.attname ORDER BY k.ord)
+				FROM unnest(co.conkey) WITH ORDINALITY AS k(attnum, ord)
+				INNER JOIN pg_catalog.pg_attribute a ON a.attrelid = co.conrelid AND a.attnum = k.attnum
+			))
+			FROM pg_catalog.pg_constraint co
+			WHERE co.conrelid = c.oid AND co.contype = 'p'
+		),
+		'unique_constraints', COALESCE((
+			SELECT jsonb_agg(jsonb_build_object('name', co.conname, 'columns', (
+				SELECT jsonb_agg(a.attname ORDER BY k.ord)
+				FROM unnest(co.conkey) WITH ORDINALITY AS k(attnum, ord)
+				INNER JOIN pg_catalog.pg_attribute a ON a.attrelid = co.conrelid AND a.attnum = k.attnum
+			)) ORDER BY co.conname)
+			FROM pg_catalog.pg_constraint co
+			WHERE co.conrelid = c.oid AND co.contype = 'u'
+		), '[]'),
+		'foreign_keys', COALESCE((
+			SELECT jsonb_agg(jsonb_build_object(
+				'name', co.conname,
+				'columns', (
+					SELECT jsonb_agg(a.attname ORDER BY k.ord)
+					FROM unnest(co.conkey) WITH ORDINALITY AS k(attnum, ord)
+					INNER JOIN pg_catalog.pg_attribute a ON a.attrelid = co.conrelid AND a.attnum = k.attnum
+				),
+				'referenced_schema', fn.nspname,
+				'referenced_table', ft.relname,
+				'referenced_columns', (
+					SELECT jsonb_agg(a.attname ORDER BY k.ord)
+					FROM unnest(co.confkey) WITH ORDINALITY AS k(attnum, ord)
+					INNER JOIN pg_catalog.pg_attribute a ON a.attrelid = co.confrelid AND a.attnum = k.attnum
+				),
+				'on_update', CASE co.confupdtype
+					WHEN 'a' THEN 'NO ACTION' WHEN 'r' THEN 'RESTRICT' WHEN 'c' THEN 'CASCADE'
+					WHEN 'n' THEN 'SET NULL' WHEN 'd' THEN 'SET DEFAULT'
+				END,
+				'on_delete', CASE co.confdeltype
+					WHEN 'a' THEN 'NO ACTION' WHEN 'r' THEN 'RESTRICT' WHEN 'c' THEN 'CASCADE'
+					WHEN 'n' THEN 'SET NULL' WHEN 'd' THEN 'SET DEFAULT'
+				END
+			) ORDER BY co.conname)
+			FROM pg_catalog.pg_constraint co
+			INNER JOIN pg_catalog.pg_class ft ON ft.oid = co.confrelid
+			INNER JOIN pg_catalog.pg_namespace fn ON fn.oid = ft.relnamespace
+			WHERE co.conrelid = c.oid AND co.contype = 'f'
+		), '[]'),
+		'check_constraints', COALESCE((
+			SELECT jsonb_agg(jsonb_build_object(
+				'name', co.conname,
+				'definition', pg_get_constraintdef(co.oid)
+			) ORDER BY co.conname)
+			FROM pg_catalog.pg_constraint co
+			WHERE co.conrelid = c.oid AND co.contype = 'c'
+		), '[]'),
+		'indexes', COALESCE((
+			SELECT jsonb_agg(jsonb_build_object(
+				'name', ic.relname,
+				'columns', (
+					SELECT jsonb_agg(a.attname ORDER BY k.ord)
+					FROM unnest(i.indkey::int2[]) WITH ORDINALITY AS k(attnum, ord)
+					LEFT JOIN pg_catalog.pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = k.attnum
+					WHERE k.ord <= i.indnkeyatts
+				),
+				'unique', i.indisunique,
+				'primary', i.indisprimary,
+				'definition', pg_get_indexdef(i.indexrelid)
+			) ORDER BY ic.relname)
+			FROM pg_catalog.pg_index i
+			INNER JOIN pg_catalog.pg_class ic ON ic.oid = i.indexrelid
+			WHERE i.indrelid = c.oid
+		), '[]')
+	)
+FROM
+	pg_catalog.pg_class c
+INNER JOIN
+	pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+WHERE
+	c.relkind IN ('r', 'p', 'v', 'm', 'f') AND
+	n.nspname = $1 AND
+	c.relname = $2`
+
+	// Function lists the overloads of a function with their input
+	// arguments in order, the arguments with defaults are the last ones
+	Function = `
+SELECT
+	p.provolatile AS "volatility",
+	p.proretset AS "set",
+	p.pronargdefaults AS "defaults",
+	CASE
+		WHEN p.prorettype = 'pg_catalog.void'::regtype THEN 'void'
+		WHEN t.typtype = 'c' OR p.proargmodes && ARRAY['o', 'b', 't']::"char"[] THEN 'composite'
+		WHEN t.typtype = 'p' THEN 'pseudo'
+		ELSE 'scalar'
+	END AS "returns",
+	COALESCE((
+		SELECT
+			json_agg(json_build_object(
+				'name', a.name,
+				'type', format_type(a.type, NULL),
+				'variadic', a.mode = 'v'
+			) ORDER BY a.ord)
+		FROM
+			unnest(COALESCE(p.proallargtypes, p.proargtypes::oid[]), p.proargmodes, p.proargnames) WITH ORDINALITY AS a(type, mode, name, ord)
+		WHERE
+			a.mode IS NULL OR a.mode IN ('i', 'b', 'v')
+	), '[]') AS "args"
+FROM
+	pg_catalog.pg_proc p
+INNER JOIN
+	pg_catalog.pg_namespace n ON n.oid = p.pronamespace
+INNER JOIN
+	pg_catalog.pg_type t ON t.oid = p.prorettype
+WHERE
+	p.prokind = 'f' AND
+	n.nspname = $1 AND
+	p.proname = $2`
 )
 
 var (
@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/prest/prest/adapters"
+	"github.com/prest/prest/adapters/postgres/statements"
+	"github.com/structy/log"
+)
+
+// QueryStreamCtx runs a query and calls fn with the json object of each
+// row as it is read from the database, the whole result is never held in
+// memory. The row is only valid until fn returns, an error returned by fn
+// stops reading the rows
+func (adapter *Postgres) QueryStreamCtx(ctx context.Context, SQL string, fn func(row []byte) error, params ...interface{}) (err error) {
+	SQL = fmt.Sprintf(statements.StreamRows, SQL)
+	log.Debugln("generated SQL:", SQL, " parameters: ", params)
+	p, err := prepareCtx(ctx, SQL)
+	if err != nil {
+		log.Errorln(err)
+		return
+	}
+	rows, err := p.QueryContext(ctx, params...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	var row sql.RawBytes
+	for rows.Next() {
+		if err = rows.Scan(&row); err != nil {
+			return
+		}
+		if err = fn(row); err != nil {
+			return
+		}
+	}
+	err = rows.Err()
+	return
+}
+
+// QueryRowsCtx runs a query and calls fn with the names of its columns,
+// in the order of the query, and then with the text of the fields of each
+// row as it is read from the database. Nulls are empty fields, the row is
+// only valid until fn returns and an error returned by fn stops reading
+// the rows. lib/pq does not support COPY TO STDOUT, the rows are read by
+// a regular query that also takes the parameters
+func (adapter *Postgres) QueryRowsCtx(ctx context.Context, SQL string, fn func(row []string) error, params ...interface{}) (err error) {
+	log.Debugln("generated SQL:", SQL, " parameters: ", params)
+	p, err := prepareCtx(ctx, SQL)
+	if err != nil {
+		log.Errorln(err)
+		return
+	}
+	rows, err := p.QueryContext(ctx, params...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return
+	}
+	if err = fn(columns); err != nil {
+		return
+	}
+	fields := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range fields {
+		dest[i] = &fields[i]
+	}
+	row := make([]string, len(columns))
+	for rows.Next() {
+		if err = rows.Scan(dest...); err != nil {
+			return
+		}
+		for i, f := range fields {
+			row[i] = f.String
+		}
+		if err = fn(row); err != nil {
+			return
+		}
+	}
+	err = rows.Err()
+	return
+}
+
+// QueryValuesCtx runs a query and calls columns with the names and the
+// types of its columns and then row with the values of each row as they
+// are read from the database, as returned by the driver: int64, float64,
+// bool, string, []byte, time.Time or nil. The values are only valid until
+// row returns and an error returned by columns or row stops reading the
+// rows
+func (adapter *Postgres) QueryValuesCtx(ctx context.Context, SQL string, columns func(cols []adapters.Column) error, row func(values []interface{}) error, params ...interface{}) (err error) {
+	log.Debugln("generated SQL:", SQL, " parameters: ", params)
+	p, err := prepareCtx(ctx, SQL)
+	if err != nil {
+		log.Errorln(err)
+		return
+	}
+	rows, err := p.QueryContext(ctx, params...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return
+	}
+	cols := make([]adapters.Column, len(types))
+	for i, t := range types {
+		cols[i] = adapters.Column{Name: t.Name(), Type: t.DatabaseTypeName()}
+		if precision, scale, ok := t.DecimalSize(); ok {
+			cols[i].Precision, cols[i].Scale = precision, scale
+		}
+	}
+	if err = columns(cols); err != nil {
+		return
+	}
+	values := make([]interface{}, len(cols))
+	dest := make([]interface{}, len(cols))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err = rows.Scan(dest...); err != nil {
+			return
+		}
+		if err = row(values); err != nil {
+			return
+		}
+	}
+	err = rows.Err()
+	return
+}
@@ -0,0 +1,159 @@
+package postgres
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// textSearchFunctions maps the key suffixes of full text search filters to
+// the function that parses the search term
+var textSearchFunctions = map[string]string{
+	"tsquery":              "to_tsquery",
+	"plainto_tsquery":      "plainto_tsquery",
+	"phraseto_tsquery":     "phraseto_tsquery",
+	"websearch_to_tsquery": "websearch_to_tsquery",
+}
+
+// textSearch is a full text search filter like `field$language:tsquery=term`
+type textSearch struct {
+	field    string
+	language string
+	function string
+	term     string
+}
+
+// parseTextSearch parses the key and the term of a full text search filter
+func parseTextSearch(key, suffix, term string) (ts textSearch, err error) {
+	function, ok := textSearchFunctions[suffix]
+	if !ok {
+		err = errors.Wrapf(ErrInvalidOperator, "%s", suffix)
+		return
+	}
+	field, language, hasLanguage := strings.Cut(key, "$")
+	if chkInvalidIdentifier(field) || (hasLanguage && chkInvalidIdentifier(language)) {
+		err = errors.Wrapf(ErrInvalidIdentifier, "%s", key)
+		return
+	}
+	ts = textSearch{field: field, language: language, function: function, term: term}
+	return
+}
+
+// query returns the tsquery of the search term, the language is bound as
+// the first placeholder when there is one
+func (ts textSearch) query(pid int) (querySQL string, values []interface{}) {
+	if ts.language == "" {
+		querySQL = fmt.Sprintf("%s($%d)", ts.function, pid)
+		values = []interface{}{ts.term}
+		return
+	}
+	querySQL = fmt.Sprintf("%s($%d::regconfig, $%d)", ts.function, pid, pid+1)
+	values = []interface{}{ts.language, ts.term}
+	return
+}
+
+// document returns the tsvector of a text field using the same language
+// of the query built with the same placeholder
+func (ts textSearch) document(field string, pid int) string {
+	if ts.language == "" {
+		return fmt.Sprintf("to_tsvector(%s)", quoteIdentifier(field))
+	}
+	return fmt.Sprintf("to_tsvector($%d::regconfig, %s)", pid, quoteIdentifier(field))
+}
+
+// where returns the condition of the full text search filter
+func (ts textSearch) where(pid int) (expr string, values []interface{}) {
+	querySQL, values := ts.query(pid)
+	expr = fmt.Sprintf("%s @@ %s", quoteIdentifier(ts.field), querySQL)
+	return
+}
+
+// textSearchesByRequest returns the full text search filters of the
+// request by field
+func textSearchesByRequest(queries url.Values) (searches map[string]textSearch, err error) {
+	searches = make(map[string]textSearch)
+	for key, val := range queries {
+		keyInfo := strings.Split(key, ":")
+		if len(keyInfo) != 2 || len(val) == 0 {
+			continue
+		}
+		if _, ok := textSearchFunctions[keyInfo[1]]; !ok {
+			continue
+		}
+		_, term := splitOperator(val[0])
+		var ts textSearch
+		ts, err = parseTextSearch(keyInfo[0], keyInfo[1], term)
+		if err != nil {
+			return nil, err
+		}
+		searches[ts.field] = ts
+	}
+	return
+}
+
+// TextSearchByRequest implements ranking and highlighting of full text
+// search filters
+//
+// `_rank=field` returns the ts_rank expression to order by the rank of
+// the search on field, `field:tsvector` ranks a tsvector column instead
+// of a text one. `_headline=field[:searched field]` returns a ts_headline
+// select expression of field highlighting the search on the same field
+// or on the searched field
+func (adapter *Postgres) TextSearchByRequest(r *http.Request, initialPlaceholderID int) (rank string, headlines []string, values []interface{}, err error) {
+	queries := r.URL.Query()
+	reqRank := queries.Get("_rank")
+	reqHeadlines := queries["_headline"]
+	if reqRank == "" && len(reqHeadlines) == 0 {
+		return
+	}
+	searches, err := textSearchesByRequest(queries)
+	if err != nil {
+		return
+	}
+
+	pid := initialPlaceholderID
+	if reqRank != "" {
+		field, isTSVector := strings.CutSuffix(reqRank, ":tsvector")
+		ts, ok := searches[field]
+		if !ok {
+			err = errors.Wrapf(ErrTextSearchNotFound, "%s", field)
+			return "", nil, nil, err
+		}
+		querySQL, queryValues := ts.query(pid)
+		document := ts.document(field, pid)
+		if isTSVector {
+			document = quoteIdentifier(field)
+		}
+		rank = fmt.Sprintf("ts_rank(%s, %s) DESC", document, querySQL)
+		values = append(values, queryValues...)
+		pid += len(queryValues)
+	}
+	for _, reqHeadline := range reqHeadlines {
+		field, searchField, _ := strings.Cut(reqHeadline, ":")
+		if searchField == "" {
+			searchField = field
+		}
+		if chkInvalidIdentifier(field) {
+			err = errors.Wrapf(ErrInvalidIdentifier, "%s", field)
+			return "", nil, nil, err
+		}
+		ts, ok := searches[searchField]
+		if !ok {
+			err = errors.Wrapf(ErrTextSearchNotFound, "%s", searchField)
+			return "", nil, nil, err
+		}
+		querySQL, queryValues := ts.query(pid)
+		document := quoteIdentifier(field)
+		if ts.language != "" {
+			document = fmt.Sprintf("$%d::regconfig, %s", pid, document)
+		}
+		name := field[strings.LastIndex(field, ".")+1:]
+		headlines = append(headlines, fmt.Sprintf(`ts_headline(%s, %s) AS "%s_headline"`, document, querySQL, name))
+		values = append(values, queryValues...)
+		pid += len(queryValues)
+	}
+	return
+}
@@ -0,0 +1,220 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/prest/prest/adapters"
+	"github.com/prest/prest/adapters/scanner"
+	"github.com/structy/log"
+)
+
+const (
+	// resolutionMerge updates the rows that conflict with the inserted ones
+	resolutionMerge = "merge-duplicates"
+	// resolutionIgnore skips the inserted rows that conflict
+	resolutionIgnore = "ignore-duplicates"
+	// upsertStagingTable is the temporary table the rows of an upsert by
+	// copy are copied to, dropped once they are inserted
+	upsertStagingTable = "prest_upsert_staging"
+)
+
+// OnConflictByRequest returns the ON CONFLICT clause of an insert, on the
+// columns of `_on_conflict` or the primary key. The resolution of the
+// `Prefer` header updates the conflicting rows (merge-duplicates, the
+// default of `_on_conflict`) with the inserted values of all the columns
+// but the target ones, or of the columns of `_on_conflict_update`, or
+// skips them (ignore-duplicates). names are the inserted columns
+func (adapter *Postgres) OnConflictByRequest(ctx context.Context, r *http.Request, schema, table, resolution, names string) (onConflictSyntax string, err error) {
+	queries := r.URL.Query()
+	reqTarget := queries.Get("_on_conflict")
+	if reqTarget == "" && resolution == "" {
+		return
+	}
+	switch resolution {
+	case "":
+		resolution = resolutionMerge
+	case resolutionMerge, resolutionIgnore:
+	default:
+		err = errors.Wrapf(ErrInvalidResolution, "%s", resolution)
+		return
+	}
+
+	var target []string
+	if reqTarget != "" {
+		target, err = onConflictColumns(reqTarget)
+	} else {
+		target, err = adapter.PrimaryKeyCtx(ctx, schema, table)
+	}
+	if err != nil {
+		return
+	}
+	columns, err := insertColumns(names)
+	if err != nil {
+		return
+	}
+	var update []string
+	if reqUpdate := queries.Get("_on_conflict_update"); reqUpdate != "" {
+		if update, err = onConflictColumns(reqUpdate); err != nil {
+			return
+		}
+		for _, column := range update {
+			if !contains(columns, column) {
+				err = errors.Wrapf(ErrInvalidOnConflict, "%s is not inserted", column)
+				return
+			}
+		}
+	} else {
+		for _, column := range columns {
+			if !contains(target, column) {
+				update = append(update, column)
+			}
+		}
+	}
+	return onConflictSQL(target, update, resolution), nil
+}
+
+// onConflictSQL returns the ON CONFLICT clause on the target columns,
+// conflicts without columns to update are skipped
+func onConflictSQL(target, update []string, resolution string) string {
+	quoted := make([]string, len(target))
+	for i, column := range target {
+		quoted[i] = pq.QuoteIdentifier(column)
+	}
+	onConflictSyntax := fmt.Sprintf("ON CONFLICT (%s) DO NOTHING", strings.Join(quoted, ", "))
+	if resolution == resolutionIgnore || len(update) == 0 {
+		return onConflictSyntax
+	}
+	set := make([]string, len(update))
+	for i, column := range update {
+		column = pq.QuoteIdentifier(column)
+		set[i] = fmt.Sprintf("%s = EXCLUDED.%s", column, column)
+	}
+	return fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(quoted, ", "), strings.Join(set, ", "))
+}
+
+// onConflictColumns parses a list of columns of the query string
+func onConflictColumns(list string) (columns []string, err error) {
+	for _, column := range strings.Split(list, ",") {
+		column = strings.TrimSpace(column)
+		if chkInvalidIdentifier(column) {
+			err = errors.Wrapf(ErrInvalidIdentifier, "%s", column)
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	return
+}
+
+// insertColumns returns the columns of the quoted names of an insert
+func insertColumns(names string) (columns []string, err error) {
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if strings.HasPrefix(name, `"`) {
+			if name, err = strconv.Unquote(name); err != nil {
+				return nil, err
+			}
+		}
+		columns = append(columns, name)
+	}
+	return
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// copyIn copies the values, a row of len(keys) values after the other,
+// with the COPY statement of copySQL
+func copyIn(tx *sql.Tx, copySQL string, keys []string, values []interface{}) (err error) {
+	stmt, err := tx.Prepare(copySQL)
+	if err != nil {
+		return
+	}
+	initOffSet := 0
+	limitOffset := len(keys)
+	for limitOffset <= len(values) {
+		if _, err = stmt.Exec(values[initOffSet:limitOffset]...); err != nil {
+			return
+		}
+		initOffSet = limitOffset
+		limitOffset += len(keys)
+	}
+	if _, err = stmt.Exec(); err != nil {
+		return
+	}
+	return stmt.Close()
+}
+
+// BatchUpsertCopyCtx copies the rows to a temporary table and inserts
+// them into the table with the onConflict clause, COPY can not resolve
+// conflicts by itself
+func (adapter *Postgres) BatchUpsertCopyCtx(ctx context.Context, dbname, schema, table string, keys []string, onConflict string, values ...interface{}) (sc adapters.Scanner) {
+	columns, err := insertColumns(strings.Join(keys, ","))
+	if err != nil {
+		log.Errorln(err)
+		return &scanner.PrestScanner{Error: err}
+	}
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = pq.QuoteIdentifier(column)
+	}
+	names := strings.Join(quoted, ", ")
+	tableSQL := fmt.Sprintf("%s.%s", pq.QuoteIdentifier(schema), pq.QuoteIdentifier(table))
+
+	tx, owned, err := beginTxCtx(ctx)
+	if err != nil {
+		log.Errorln(err)
+		return &scanner.PrestScanner{Error: err}
+	}
+	defer func() {
+		if !owned {
+			return
+		}
+		if err != nil {
+			if txerr := tx.Rollback(); txerr != nil {
+				log.Errorln(txerr)
+			}
+			return
+		}
+		if txerr := tx.Commit(); txerr != nil {
+			log.Errorln(txerr)
+		}
+	}()
+	// the staging table takes the types but not the constraints of the
+	// columns, the missing ones get their defaults on the insert
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(
+		"CREATE TEMPORARY TABLE %s ON COMMIT DROP AS SELECT %s FROM %s WITH NO DATA",
+		upsertStagingTable, names, tableSQL))
+	if err != nil {
+		log.Errorln(err)
+		return &scanner.PrestScanner{Error: err}
+	}
+	if err = copyIn(tx, pq.CopyIn(upsertStagingTable, columns...), columns, values); err != nil {
+		log.Errorln(err)
+		return &scanner.PrestScanner{Error: err}
+	}
+	SQL := fmt.Sprintf("INSERT INTO %s(%s) SELECT %s FROM %s %s", tableSQL, names, names, upsertStagingTable, onConflict)
+	log.Debugln("generated SQL:", SQL)
+	if _, err = tx.ExecContext(ctx, SQL); err != nil {
+		log.Errorln(err)
+		return &scanner.PrestScanner{Error: err}
+	}
+	// the transaction of the request may run an other upsert by copy
+	if _, err = tx.ExecContext(ctx, fmt.Sprintf("DROP TABLE %s", upsertStagingTable)); err != nil {
+		log.Errorln(err)
+		return &scanner.PrestScanner{Error: err}
+	}
+	return &scanner.PrestScanner{}
+}
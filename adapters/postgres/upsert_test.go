@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnConflictByRequest(t *testing.T) {
+	var testCases = []struct {
+		description string
+		url         string
+		resolution  string
+		onConflict  string
+		err         error
+	}{
+		{"No upsert", "/prest/public/test", "", "", nil},
+		{"Update the other columns", "/prest/public/test?_on_conflict=id", "", `ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name", "age" = EXCLUDED."age"`, nil},
+		{"Update chosen columns", "/prest/public/test?_on_conflict=id&_on_conflict_update=age", "merge-duplicates", `ON CONFLICT ("id") DO UPDATE SET "age" = EXCLUDED."age"`, nil},
+		{"Composite target", "/prest/public/test?_on_conflict=id,name", "", `ON CONFLICT ("id", "name") DO UPDATE SET "age" = EXCLUDED."age"`, nil},
+		{"Ignore duplicates", "/prest/public/test?_on_conflict=name", "ignore-duplicates", `ON CONFLICT ("name") DO NOTHING`, nil},
+		{"Nothing to update", "/prest/public/test?_on_conflict=id,name,age", "", `ON CONFLICT ("id", "name", "age") DO NOTHING`, nil},
+		{"Invalid resolution", "/prest/public/test?_on_conflict=id", "replace", "", ErrInvalidResolution},
+		{"Invalid target", "/prest/public/test?_on_conflict=0id", "", "", ErrInvalidIdentifier},
+		{"Update of a column not inserted", "/prest/public/test?_on_conflict=id&_on_conflict_update=email", "", "", ErrInvalidOnConflict},
+	}
+
+	adapter := &Postgres{}
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			r := httptest.NewRequest("POST", tc.url, nil)
+			onConflict, err := adapter.OnConflictByRequest(context.Background(), r, "public", "test", tc.resolution, `"id", "name", "age"`)
+			require.ErrorIs(t, err, tc.err)
+			require.Equal(t, tc.onConflict, onConflict)
+		})
+	}
+}
+
+func TestInsertColumns(t *testing.T) {
+	columns, err := insertColumns(`"id","first name"`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"id", "first name"}, columns)
+
+	_, err = insertColumns(`"id`)
+	require.Error(t, err)
+}
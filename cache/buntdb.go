@@ -31,17 +31,43 @@ func BuntConnect(key string) (db *buntdb.DB, err error) {
 // BuntGet downloads the data - if any - that is in the buntdb (embedded cache database)
 // using response.URL.String() as key
 func BuntGet(key string, w http.ResponseWriter) (cacheExist bool) {
+	val, cacheExist := buntValue(key)
+	if cacheExist {
+		w.Header().Set("Cache-Server", "prestd")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(val))
+	}
+	return
+}
+
+// BuntGetETag downloads the data like BuntGet along with the ETag of the
+// json body, the one of the response that was cached, and returns 304 when
+// it is one of the If-None-Match header
+func BuntGetETag(key string, w http.ResponseWriter, r *http.Request) (cacheExist bool) {
+	val, cacheExist := buntValue(key)
+	if !cacheExist {
+		return
+	}
+	etag := ETag([]byte(val))
+	w.Header().Set("Cache-Server", "prestd")
+	w.Header().Set("ETag", etag)
+	if !NoneMatch(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(val))
+	return
+}
+
+// buntValue returns the data cached on the key, if any
+func buntValue(key string) (val string, cacheExist bool) {
 	db, _ := BuntConnect(key)
-	cacheExist = false
 	//nolint:errcheck
 	db.View(func(tx *buntdb.Tx) error {
-		val, err := tx.Get(key)
-		if err == nil {
-			cacheExist = true
-			w.Header().Set("Cache-Server", "prestd")
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(val))
-		}
+		var err error
+		val, err = tx.Get(key)
+		cacheExist = err == nil
 		return nil
 	})
 	defer db.Close()
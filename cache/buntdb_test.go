@@ -1,11 +1,13 @@
 package cache
 
 import (
+	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/prest/prest/adapters/postgres"
 	"github.com/prest/prest/config"
+	"github.com/stretchr/testify/require"
 )
 
 func init() {
@@ -23,3 +25,25 @@ func TestBuntGetDoesntExist(t *testing.T) {
 		t.Errorf("expected cache non-existent, but got %t", cache)
 	}
 }
+
+func TestBuntGetETag(t *testing.T) {
+	cacheConf := config.PrestConf.Cache
+	t.Cleanup(func() { config.PrestConf.Cache = cacheConf })
+	config.PrestConf.Cache.Enabled = true
+	config.PrestConf.Cache.Time = 10
+	config.PrestConf.Cache.StoragePath = t.TempDir()
+	BuntSet("/prest/public/test", `[{"id": 1}]`)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/prest/public/test", nil)
+	require.True(t, BuntGetETag("/prest/public/test", w, r))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, ETag([]byte(`[{"id": 1}]`)), w.Header().Get("ETag"))
+	require.Equal(t, `[{"id": 1}]`, w.Body.String())
+
+	r.Header.Set("If-None-Match", w.Header().Get("ETag"))
+	w = httptest.NewRecorder()
+	require.True(t, BuntGetETag("/prest/public/test", w, r))
+	require.Equal(t, http.StatusNotModified, w.Code)
+	require.Empty(t, w.Body.String())
+}
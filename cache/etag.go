@@ -0,0 +1,34 @@
+package cache
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// ETag returns the strong ETag of a json response, the md5 of its body.
+// The body of a row is the text of its jsonb object, so the ETag is the one
+// the database computes to check If-Match
+func ETag(body []byte) string {
+	sum := md5.Sum(body)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// NoneMatch returns false when the ETag is one of the If-None-Match
+// header, compared weakly, or the header is `*`
+func NoneMatch(r *http.Request, etag string) bool {
+	header := strings.TrimSpace(r.Header.Get("If-None-Match"))
+	if header == "" {
+		return true
+	}
+	if header == "*" {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == etag {
+			return false
+		}
+	}
+	return true
+}
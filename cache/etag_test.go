@@ -0,0 +1,34 @@
+package cache
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoneMatch(t *testing.T) {
+	etag := ETag([]byte(`{"id": 1, "name": "prest"}`))
+
+	var testCases = []struct {
+		description string
+		header      string
+		none        bool
+	}{
+		{"No If-None-Match", "", true},
+		{"Same ETag", etag, false},
+		{"Weak ETag", "W/" + etag, false},
+		{"One of several ETags", `"abc", ` + etag, false},
+		{"Other ETag", `"abc"`, true},
+		{"Any ETag", "*", false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/prest/public/test/1", nil)
+			if tc.header != "" {
+				r.Header.Set("If-None-Match", tc.header)
+			}
+			require.Equal(t, tc.none, NoneMatch(r, etag))
+		})
+	}
+}
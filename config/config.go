@@ -18,6 +18,9 @@ type TablesConf struct {
 	Name        string   `mapstructure:"name"`
 	Permissions []string `mapstructure:"permissions"`
 	Fields      []string `mapstructure:"fields"`
+	// SoftDelete is the timestamp column set by the deletes instead of
+	// removing the rows, the rows where it is set are hidden from reads
+	SoftDelete string `mapstructure:"soft_delete"`
 }
 
 // Cache structure for storing cache system configuration
@@ -108,6 +111,8 @@ type Prest struct {
 	HTTPSCert            string
 	HTTPSKey             string
 	Cache                Cache
+	TxTimeout            int
+	TxMax                int
 	PluginPath           string
 	PluginMiddlewareList []PluginMiddleware
 }
@@ -175,6 +180,9 @@ func viperCfg() {
 	viper.SetDefault("cache.storagepath", "./")
 	viper.SetDefault("cache.sufixfile", ".cache.prestd.db")
 
+	viper.SetDefault("tx.timeout", 60)
+	viper.SetDefault("tx.max", 5)
+
 	viper.SetDefault("version", 1)
 	viper.SetDefault("debug", false)
 	viper.SetDefault("context", "/")
@@ -276,6 +284,8 @@ func Parse(cfg *Prest) {
 	cfg.Cache.Time = viper.GetInt("cache.time")
 	cfg.Cache.StoragePath = viper.GetString("cache.storagepath")
 	cfg.Cache.SufixFile = viper.GetString("cache.sufixfile")
+	cfg.TxTimeout = viper.GetInt("tx.timeout")
+	cfg.TxMax = viper.GetInt("tx.max")
 	cfg.ExposeConf.Enabled = viper.GetBool("expose.enabled")
 	cfg.ExposeConf.TableListing = viper.GetBool("expose.tables")
 	cfg.ExposeConf.SchemaListing = viper.GetBool("expose.schemas")
@@ -38,6 +38,8 @@ func TestParse(t *testing.T) {
 		require.Equal(t, false, cfg.Debug)
 		require.Equal(t, 1, cfg.Version)
 		require.Equal(t, true, cfg.AccessConf.Restrict)
+		require.Equal(t, 60, cfg.TxTimeout)
+		require.Equal(t, 5, cfg.TxMax)
 	})
 
 	t.Run("PREST_CONF", func(t *testing.T) {
@@ -0,0 +1,305 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prest/prest/config"
+	pctx "github.com/prest/prest/context"
+	"github.com/prest/prest/middlewares/statements"
+	"github.com/prest/prest/renderers"
+	"github.com/structy/log"
+)
+
+// batchOperation is an operation of a transactional batch on a table, the
+// database is the configured one when empty. The query, body and pk may
+// reference the results of the previous operations
+type batchOperation struct {
+	Operation string          `json:"operation"`
+	Database  string          `json:"database"`
+	Schema    string          `json:"schema"`
+	Table     string          `json:"table"`
+	PK        string          `json:"pk"`
+	Query     string          `json:"query"`
+	Body      json.RawMessage `json:"body"`
+}
+
+// batchHandler is the handler, method and permission of an operation
+type batchHandler struct {
+	handler    http.HandlerFunc
+	method     string
+	permission string
+}
+
+var batchHandlers = map[string]batchHandler{
+	"select": {SelectFromTables, http.MethodGet, statements.READ},
+	"insert": {InsertInTables, http.MethodPost, statements.WRITE},
+	"update": {UpdateTable, http.MethodPatch, statements.WRITE},
+	"delete": {DeleteFromTable, http.MethodDelete, statements.DELETE},
+}
+
+// batchReference matches the references to the results of the previous
+// operations, `${index.column}`, e.g. `${0.id}`
+var batchReference = regexp.MustCompile(`\$\{(\d+)((?:\.[^.}]+)*)\}`)
+
+// batchQuotedReference matches the json strings holding only a reference
+var batchQuotedReference = regexp.MustCompile(`"` + batchReference.String() + `"`)
+
+// Batch runs the operations of the body in one transaction, in order. The
+// results of the operations are returned when all of them succeed, the
+// transaction is rolled back on the first failing one, whose index is
+// sent on the Prest-Batch-Index header
+func Batch(w http.ResponseWriter, r *http.Request) {
+	var operations []batchOperation
+	if err := json.NewDecoder(r.Body).Decode(&operations); err != nil {
+		err = fmt.Errorf("could not perform Batch: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(operations) == 0 {
+		http.Error(w, "could not perform Batch: no operations", http.StatusBadRequest)
+		return
+	}
+
+	database := config.PrestConf.Adapter.GetDatabase()
+	if operations[0].Database != "" {
+		database = operations[0].Database
+	}
+	for i, op := range operations {
+		status, err := checkBatchOperation(op, database)
+		if err != nil {
+			batchError(w, i, err, status)
+			return
+		}
+	}
+
+	ctx := context.WithValue(r.Context(), pctx.DBNameKey, database)
+	timeout, _ := ctx.Value(pctx.HTTPTimeoutKey).(int)
+	ctx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(timeout))
+	defer cancel()
+
+	tx, err := config.PrestConf.Adapter.GetTransactionCtx(ctx)
+	if err != nil {
+		err = fmt.Errorf("could not perform Batch: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ctx = context.WithValue(ctx, pctx.TxKey, tx)
+
+	results := make([]json.RawMessage, 0, len(operations))
+	for i, op := range operations {
+		result, status, err := runBatchOperation(ctx, op, database, results)
+		if err != nil {
+			if txerr := tx.Rollback(); txerr != nil {
+				log.Errorln(txerr)
+			}
+			batchError(w, i, err, status)
+			return
+		}
+		results = append(results, result)
+	}
+	// the writes are kept only when the results can be sent
+	body, err := json.Marshal(results)
+	if err != nil {
+		if txerr := tx.Rollback(); txerr != nil {
+			log.Errorln(txerr)
+		}
+		err = fmt.Errorf("could not perform Batch: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err = tx.Commit(); err != nil {
+		err = fmt.Errorf("could not perform Batch: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Write(body)
+}
+
+// batchError writes the error of the operation of index i
+func batchError(w http.ResponseWriter, i int, err error, status int) {
+	w.Header().Set("Prest-Batch-Index", strconv.Itoa(i))
+	http.Error(w, fmt.Sprintf("operation %d: %v", i, err), status)
+}
+
+// checkBatchOperation checks an operation before the transaction begins,
+// every operation runs on the database of the batch and returns json
+func checkBatchOperation(op batchOperation, database string) (status int, err error) {
+	bh, ok := batchHandlers[op.Operation]
+	if !ok {
+		return http.StatusBadRequest, fmt.Errorf("invalid operation %q", op.Operation)
+	}
+	if op.Schema == "" || op.Table == "" {
+		return http.StatusBadRequest, errors.New("schema and table are required")
+	}
+	if op.Database != "" && op.Database != database {
+		return http.StatusBadRequest, errors.New("the operations of a batch run on one database")
+	}
+	queries, err := url.ParseQuery(op.Query)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	if format := queries.Get("_renderer"); format != "" && format != renderers.JSON && format != renderers.Object {
+		return http.StatusBadRequest, fmt.Errorf("_renderer=%s can not be used in a batch", format)
+	}
+	if queries.Has("_stream") {
+		return http.StatusBadRequest, errors.New("_stream can not be used in a batch")
+	}
+	if !config.PrestConf.Adapter.TablePermissions(op.Table, bh.permission) {
+		return http.StatusUnauthorized, fmt.Errorf("required authorization to table %s", op.Table)
+	}
+	return
+}
+
+// runBatchOperation runs an operation with the handler of its route in the
+// transaction of ctx, and returns the body of its response
+func runBatchOperation(ctx context.Context, op batchOperation, database string, results []json.RawMessage) (result json.RawMessage, status int, err error) {
+	status = http.StatusBadRequest
+	query, err := resolveBatchReferences(op.Query, results, url.QueryEscape)
+	if err != nil {
+		return
+	}
+	pk, err := resolveBatchReferences(op.PK, results, nil)
+	if err != nil {
+		return
+	}
+	body, err := resolveBatchBody(op.Body, results)
+	if err != nil {
+		return
+	}
+
+	vars := map[string]string{
+		"database": database,
+		"schema":   op.Schema,
+		"table":    op.Table,
+	}
+	path := fmt.Sprintf("/%s/%s/%s", database, op.Schema, op.Table)
+	if pk != "" {
+		vars["pk"] = pk
+		path = fmt.Sprintf("%s/%s", path, url.PathEscape(pk))
+	}
+	bh := batchHandlers[op.Operation]
+	u := url.URL{Path: path, RawQuery: query}
+	req, err := http.NewRequestWithContext(ctx, bh.method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req = mux.SetURLVars(req, vars)
+
+	recorder := httptest.NewRecorder()
+	bh.handler(recorder, req)
+	if recorder.Code >= 400 {
+		return nil, recorder.Code, errors.New(strings.TrimSpace(recorder.Body.String()))
+	}
+	result = recorder.Body.Bytes()
+	// rows skipped on conflict have no body
+	if len(bytes.TrimSpace(result)) == 0 {
+		result = json.RawMessage("null")
+	}
+	if !json.Valid(result) {
+		return nil, http.StatusBadRequest, errors.New("the result is not json")
+	}
+	return result, recorder.Code, nil
+}
+
+// resolveBatchBody replaces the references of the body, a string holding
+// only a reference takes the referenced json value
+func resolveBatchBody(body json.RawMessage, results []json.RawMessage) (resolved []byte, err error) {
+	if len(body) == 0 {
+		return
+	}
+	resolved = batchQuotedReference.ReplaceAllFunc(body, func(ref []byte) []byte {
+		value, refErr := batchValue(string(ref[1:len(ref)-1]), results)
+		if refErr != nil {
+			err = refErr
+			return ref
+		}
+		return value
+	})
+	if err != nil {
+		return nil, err
+	}
+	// references within strings are json escaped
+	s, err := resolveBatchReferences(string(resolved), results, func(text string) string {
+		escaped, _ := json.Marshal(text)
+		return string(escaped[1 : len(escaped)-1])
+	})
+	return []byte(s), err
+}
+
+// resolveBatchReferences replaces the references of s by the text of the
+// referenced values, escaped by escape when not nil
+func resolveBatchReferences(s string, results []json.RawMessage, escape func(string) string) (resolved string, err error) {
+	resolved = batchReference.ReplaceAllStringFunc(s, func(ref string) string {
+		value, refErr := batchValue(ref, results)
+		if refErr != nil {
+			err = refErr
+			return ref
+		}
+		text := string(value)
+		var str string
+		if json.Unmarshal(value, &str) == nil {
+			text = str
+		}
+		if escape != nil {
+			text = escape(text)
+		}
+		return text
+	})
+	return
+}
+
+// batchValue returns the json value of a reference, the columns of a
+// result holding rows are read from its first row unless indexed, e.g.
+// `${1.2.id}`
+func batchValue(ref string, results []json.RawMessage) (value json.RawMessage, err error) {
+	match := batchReference.FindStringSubmatch(ref)
+	index, _ := strconv.Atoi(match[1])
+	if index >= len(results) {
+		return nil, fmt.Errorf("invalid reference %s: operation %d did not run before", ref, index)
+	}
+	value = results[index]
+	path := strings.Split(strings.TrimPrefix(match[2], "."), ".")
+	if match[2] == "" {
+		path = nil
+	}
+	for _, key := range path {
+		var rows []json.RawMessage
+		if json.Unmarshal(value, &rows) == nil {
+			i, convErr := strconv.Atoi(key)
+			if convErr == nil {
+				if i >= len(rows) {
+					return nil, fmt.Errorf("invalid reference %s: no row %d", ref, i)
+				}
+				value = rows[i]
+				continue
+			}
+			if len(rows) == 0 {
+				return nil, fmt.Errorf("invalid reference %s: no rows", ref)
+			}
+			value = rows[0]
+		}
+		var columns map[string]json.RawMessage
+		if err = json.Unmarshal(value, &columns); err != nil {
+			return nil, fmt.Errorf("invalid reference %s: %v", ref, err)
+		}
+		var ok bool
+		if value, ok = columns[key]; !ok {
+			return nil, fmt.Errorf("invalid reference %s: no %s", ref, key)
+		}
+	}
+	return
+}
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveBatchReferences(t *testing.T) {
+	results := []json.RawMessage{
+		json.RawMessage(`{"id":7,"name":"prest batch"}`),
+		json.RawMessage(`[{"id":8,"name":"a"},{"id":9,"name":"b"}]`),
+		json.RawMessage(`[]`),
+	}
+
+	var testCases = []struct {
+		description string
+		query       string
+		body        string
+		resolved    string
+		resolvedBdy string
+		err         bool
+	}{
+		{"No references", "name=$eq.prest", `{"name":"prest"}`, "name=$eq.prest", `{"name":"prest"}`, false},
+		{"Column of an object", "id=$eq.${0.id}", `{"id":"${0.id}"}`, "id=$eq.7", `{"id":7}`, false},
+		{"Column of the first row", "id=$eq.${1.id}", `{"id":"${1.id}"}`, "id=$eq.8", `{"id":8}`, false},
+		{"Column of an indexed row", "id=$eq.${1.1.id}", `{"id":"${1.1.id}"}`, "id=$eq.9", `{"id":9}`, false},
+		{"Reference within a string", "name=$eq.${0.name}", `{"name":"copy of ${0.name}"}`, "name=$eq.prest+batch", `{"name":"copy of prest batch"}`, false},
+		{"Whole result", "", `{"data":"${1}"}`, "", `{"data":[{"id":8,"name":"a"},{"id":9,"name":"b"}]}`, false},
+		{"Operation not run", "id=$eq.${3.id}", `{}`, "", "", true},
+		{"Missing column", "id=$eq.${0.age}", `{}`, "", "", true},
+		{"No rows", "id=$eq.${2.id}", `{}`, "", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			query, err := resolveBatchReferences(tc.query, results, url.QueryEscape)
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.resolved, query)
+			body, err := resolveBatchBody(json.RawMessage(tc.body), results)
+			require.NoError(t, err)
+			require.JSONEq(t, tc.resolvedBdy, string(body))
+		})
+	}
+}
+
+func TestBatch(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/_batch", setHTTPTimeoutMiddleware(Batch)).Methods("POST")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		operations  []map[string]interface{}
+		status      int
+		index       string
+	}{
+		{"execute batch", []map[string]interface{}{
+			{"operation": "insert", "schema": "public", "table": "test4", "body": map[string]interface{}{"name": "prest-batch"}},
+			{"operation": "update", "schema": "public", "table": "test4", "pk": "${0.id}", "query": "_returning=*", "body": map[string]interface{}{"name": "prest-batch-updated"}},
+			{"operation": "select", "schema": "public", "table": "test4", "query": "name=$eq.${1.name}"},
+			{"operation": "delete", "schema": "public", "table": "test4", "query": "id=$eq.${2.id}"},
+		}, http.StatusOK, ""},
+		{"execute batch rolled back", []map[string]interface{}{
+			{"operation": "insert", "schema": "public", "table": "test4", "body": map[string]interface{}{"name": "prest-batch-rollback"}},
+			{"operation": "insert", "schema": "public", "table": "test4", "body": map[string]interface{}{"name": "prest-batch-rollback"}},
+		}, http.StatusBadRequest, "1"},
+		{"execute batch with an invalid reference", []map[string]interface{}{
+			{"operation": "select", "schema": "public", "table": "test4", "query": "id=$eq.${1.id}"},
+		}, http.StatusBadRequest, "0"},
+		{"execute batch with an invalid operation", []map[string]interface{}{
+			{"operation": "upsert", "schema": "public", "table": "test4"},
+		}, http.StatusBadRequest, "0"},
+		{"execute batch on several databases", []map[string]interface{}{
+			{"operation": "select", "database": "prest-test", "schema": "public", "table": "test4"},
+			{"operation": "select", "database": "secondary-db", "schema": "public", "table": "test4"},
+		}, http.StatusBadRequest, "1"},
+		{"execute batch with a select rendered as csv", []map[string]interface{}{
+			{"operation": "insert", "schema": "public", "table": "test4", "body": map[string]interface{}{"name": "prest-batch-csv"}},
+			{"operation": "select", "schema": "public", "table": "test4", "query": "_renderer=csv"},
+		}, http.StatusBadRequest, "1"},
+		{"execute batch with a streamed select", []map[string]interface{}{
+			{"operation": "select", "schema": "public", "table": "test4", "query": "_stream=ndjson"},
+		}, http.StatusBadRequest, "0"},
+		{"execute batch without permission", []map[string]interface{}{
+			{"operation": "delete", "schema": "public", "table": "test_readonly_access"},
+		}, http.StatusUnauthorized, "0"},
+		{"execute batch without operations", []map[string]interface{}{}, http.StatusBadRequest, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			byt, err := json.Marshal(tc.operations)
+			require.NoError(t, err)
+			resp, err := http.Post(server.URL+"/_batch", "application/json", bytes.NewReader(byt))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			require.Equal(t, tc.status, resp.StatusCode)
+			require.Equal(t, tc.index, resp.Header.Get("Prest-Batch-Index"))
+		})
+	}
+
+	t.Run("rolled back rows are not inserted", func(t *testing.T) {
+		byt, err := json.Marshal([]map[string]interface{}{
+			{"operation": "select", "schema": "public", "table": "test4", "query": "name=$eq.prest-batch-rollback"},
+		})
+		require.NoError(t, err)
+		resp, err := http.Post(server.URL+"/_batch", "application/json", bytes.NewReader(byt))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var results [][]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&results))
+		require.Len(t, results, 1)
+		require.Empty(t, results[0])
+	})
+}
@@ -0,0 +1,25 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/prest/prest/config"
+)
+
+var errIfMatchWithoutKey = errors.New("If-Match can only be sent to the route of a row")
+
+// ifMatchWhere returns the condition of the If-Match header, checked by
+// the update or the delete itself so that a row changed since it was read
+// is not matched
+func ifMatchWhere(r *http.Request, table string, initialPlaceholderID int) (where string, values []interface{}, err error) {
+	if r.Header.Get("If-Match") == "" {
+		return
+	}
+	if _, byKey := mux.Vars(r)["pk"]; !byKey {
+		err = errIfMatchWithoutKey
+		return
+	}
+	return config.PrestConf.Adapter.IfMatchByRequest(r, table, initialPlaceholderID)
+}
@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestETag(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/{schema}/{table}", setHTTPTimeoutMiddleware(InsertInTables)).Methods("POST")
+	router.HandleFunc("/{database}/{schema}/{table}", setHTTPTimeoutMiddleware(UpdateTable)).Methods("PATCH")
+	router.HandleFunc("/{database}/{schema}/{table}/{pk}", setHTTPTimeoutMiddleware(SelectFromTables)).Methods("GET")
+	router.HandleFunc("/{database}/{schema}/{table}/{pk}", setHTTPTimeoutMiddleware(UpdateTable)).Methods("PATCH")
+	router.HandleFunc("/{database}/{schema}/{table}/{pk}", setHTTPTimeoutMiddleware(DeleteFromTable)).Methods("DELETE")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	do := func(method, url string, header map[string]string, body interface{}) *http.Response {
+		byt, err := json.Marshal(body)
+		require.NoError(t, err)
+		req, err := http.NewRequest(method, server.URL+url, bytes.NewReader(byt))
+		require.NoError(t, err)
+		for key, value := range header {
+			req.Header.Set(key, value)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	resp := do("POST", "/prest-test/public/test4", nil, map[string]interface{}{"name": "prest-etag"})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var row struct {
+		ID int `json:"id"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&row))
+	url := fmt.Sprintf("/prest-test/public/test4/%d", row.ID)
+
+	resp = do("GET", url, nil, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	etag := resp.Header.Get("ETag")
+	require.NotEmpty(t, etag)
+
+	resp = do("GET", url, map[string]string{"If-None-Match": etag}, nil)
+	require.Equal(t, http.StatusNotModified, resp.StatusCode)
+	require.Equal(t, etag, resp.Header.Get("ETag"))
+
+	// the body is not the row that If-Match compares
+	resp = do("GET", url+"?_select=max:name", nil, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Empty(t, resp.Header.Get("ETag"))
+
+	resp = do("PATCH", url, map[string]string{"If-Match": etag}, map[string]interface{}{"name": "prest-etag-updated"})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// the row changed since the ETag was read
+	resp = do("PATCH", url, map[string]string{"If-Match": etag}, map[string]interface{}{"name": "prest-etag-lost"})
+	require.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+	resp = do("DELETE", url, map[string]string{"If-Match": etag}, nil)
+	require.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+
+	resp = do("GET", url, map[string]string{"If-None-Match": etag}, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	etag = resp.Header.Get("ETag")
+
+	resp = do("PATCH", "/prest-test/public/test4?id=$eq."+fmt.Sprint(row.ID), map[string]string{"If-Match": etag}, map[string]interface{}{"name": "prest-etag-lost"})
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = do("DELETE", url, map[string]string{"If-Match": etag}, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
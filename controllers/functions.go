@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prest/prest/adapters/postgres"
+	"github.com/prest/prest/config"
+	pctx "github.com/prest/prest/context"
+)
+
+// CallFunction calls a stored function with the named arguments of the
+// json body of a POST, or of the query string of a GET for stable and
+// immutable functions, and writes its result
+func CallFunction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	database := vars["database"]
+	schema := vars["schema"]
+	function := vars["function"]
+
+	if config.PrestConf.SingleDB && (config.PrestConf.Adapter.GetDatabase() != database) {
+		err := fmt.Errorf("database not registered: %v", database)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !config.PrestConf.Adapter.FunctionPermissions(schema, function) {
+		err := fmt.Errorf("required authorization to function %s", function)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	args, err := config.PrestConf.Adapter.FunctionArgsByRequest(r)
+	if err != nil {
+		err = fmt.Errorf("could not perform FunctionArgsByRequest: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), pctx.DBNameKey, database)
+
+	timeout, _ := ctx.Value(pctx.HTTPTimeoutKey).(int)
+	ctx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(timeout))
+	defer cancel()
+
+	sc := config.PrestConf.Adapter.CallFunctionCtx(ctx, schema, function, args, r.Method == http.MethodGet)
+	if err = sc.Err(); err != nil {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, postgres.ErrFunctionNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, postgres.ErrFunctionVolatile):
+			status = http.StatusMethodNotAllowed
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Write(sc.Bytes())
+}
@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prest/prest/testutils"
+)
+
+func TestCallFunction(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/_RPC/{database}/{schema}/{function}", setHTTPTimeoutMiddleware(CallFunction)).Methods("GET", "POST")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var testCases = []struct {
+		description string
+		url         string
+		method      string
+		request     map[string]interface{}
+		status      int
+		body        []string
+	}{
+		{"call scalar function", "/_RPC/prest-test/public/add_numbers", "POST", map[string]interface{}{"a": 1, "b": 2}, http.StatusOK, []string{"3"}},
+		{"call scalar function with a default", "/_RPC/prest-test/public/add_numbers?a=41", "GET", nil, http.StatusOK, []string{"42"}},
+		{"call set-returning function", "/_RPC/prest-test/public/test4_by_name?search=prest-rpc-missing%25", "GET", nil, http.StatusOK, []string{"[]"}},
+		{"call volatile function", "/_RPC/prest-test/public/insert_test4", "POST", map[string]interface{}{"new_name": "prest-rpc"}, http.StatusOK, nil},
+		{"call volatile function with GET", "/_RPC/prest-test/public/insert_test4?new_name=prest-rpc-get", "GET", nil, http.StatusMethodNotAllowed, nil},
+		{"call function with an unknown argument", "/_RPC/prest-test/public/add_numbers", "POST", map[string]interface{}{"c": 1}, http.StatusNotFound, nil},
+		{"call function of a system schema", "/_RPC/prest-test/pg_catalog/pg_sleep", "POST", map[string]interface{}{"seconds": 0}, http.StatusUnauthorized, nil},
+		{"call function without the execute permission", "/_RPC/prest-test/public/missing_function", "POST", nil, http.StatusUnauthorized, nil},
+		{"call function with an invalid argument", "/_RPC/prest-test/public/add_numbers", "POST", map[string]interface{}{"a": "prest"}, http.StatusBadRequest, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		testutils.DoRequest(t, server.URL+tc.url, tc.request, tc.method, tc.status, tc.description, tc.body...)
+	}
+}
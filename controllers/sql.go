@@ -10,34 +10,44 @@ import (
 	"github.com/prest/prest/cache"
 	"github.com/prest/prest/config"
 	pctx "github.com/prest/prest/context"
+	"github.com/prest/prest/renderers"
+	"github.com/structy/log"
 )
 
 // ExecuteScriptQuery is a function to execute and return result of script query
 func ExecuteScriptQuery(rq *http.Request, queriesPath string, script string) ([]byte, error) {
+	sql, values, err := parseScriptQuery(rq, queriesPath, script)
+	if err != nil {
+		return nil, err
+	}
+
+	sc := config.PrestConf.Adapter.ExecuteScriptsCtx(rq.Context(), rq.Method, sql, values)
+	if sc.Err() != nil {
+		err = fmt.Errorf("could not execute sql, check your prest logs")
+		return nil, err
+	}
+
+	return sc.Bytes(), nil
+}
+
+// parseScriptQuery returns the sql and the values of a script query
+func parseScriptQuery(rq *http.Request, queriesPath string, script string) (sql string, values []interface{}, err error) {
 	config.PrestConf.Adapter.SetDatabase(config.PrestConf.PGDatabase)
 	sqlPath, err := config.PrestConf.Adapter.GetScript(rq.Method, queriesPath, script)
 	if err != nil {
 		err = fmt.Errorf("could not get script %s/%s, %v", queriesPath, script, err)
-		return nil, err
+		return
 	}
 
 	templateData := make(map[string]interface{})
 	extractHeaders(rq, templateData)
 	extractQueryParameters(rq, templateData)
 
-	sql, values, err := config.PrestConf.Adapter.ParseScript(sqlPath, templateData)
+	sql, values, err = config.PrestConf.Adapter.ParseScript(sqlPath, templateData)
 	if err != nil {
 		err = fmt.Errorf("could not parse script %s/%s, %v", queriesPath, script, err)
-		return nil, err
-	}
-
-	sc := config.PrestConf.Adapter.ExecuteScriptsCtx(rq.Context(), rq.Method, sql, values)
-	if sc.Err() != nil {
-		err = fmt.Errorf("could not execute sql, check your prest logs")
-		return nil, err
 	}
-
-	return sc.Bytes(), nil
+	return
 }
 
 // ExecuteFromScripts is a controller to peform SQL in scripts created by users
@@ -58,6 +68,22 @@ func ExecuteFromScripts(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(timeout))
 	defer cancel()
 
+	// csv, tsv, arrow and parquet reads are written as the rows are read,
+	// without cache
+	if format, _ := renderers.Format(r); r.Method == "GET" && (renderers.IsTabular(format) || renderers.IsColumnar(format)) {
+		sql, values, err := parseScriptQuery(r.WithContext(ctx), queriesPath, script)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err = streamDownload(ctx, w, format, script, sql, values); err != nil {
+			log.Errorln(err)
+			err = fmt.Errorf("could not execute sql, check your prest logs")
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
 	result, err := ExecuteScriptQuery(r.WithContext(ctx), queriesPath, script)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -65,6 +91,13 @@ func ExecuteFromScripts(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if r.Method == "GET" {
+		if r.URL.Query().Get("_single") == "true" {
+			result, err = renderers.SingleObject(result)
+			if err != nil {
+				http.Error(w, err.Error(), singleObjectStatus(err))
+				return
+			}
+		}
 		// Cache arrow if enabled
 		cache.BuntSet(r.URL.String(), string(result))
 	}
@@ -65,6 +65,8 @@ func TestExecuteFromScripts(t *testing.T) {
 		{"Get results using scripts and funcs by GET method", "/_QUERIES/fulltable/funcs", "GET", http.StatusOK},
 		{"Get results using scripts by GET method", "/_QUERIES/fulltable/get_all?field1=gopher", "GET", http.StatusOK},
 		{"Get results using scripts by GET method (2)", "/_QUERIES/fulltable/get_header", "GET", http.StatusOK},
+		{"Get results using scripts by GET method as parquet", "/_QUERIES/fulltable/get_all?field1=gopher&_renderer=parquet", "GET", http.StatusOK},
+		{"Get results using scripts by GET method as csv", "/_QUERIES/fulltable/get_all?field1=gopher&_renderer=csv", "GET", http.StatusOK},
 		{"Get results using scripts by POST method", "/_QUERIES/fulltable/write_all?field1=gopherzin&field2=pereira", "POST", http.StatusOK},
 		{"Get results using scripts by PUT method", "/_QUERIES/fulltable/put_all?field1=trump&field2=pereira", "PUT", http.StatusOK},
 		{"Get results using scripts by PATCH method", "/_QUERIES/fulltable/patch_all?field1=temer&field2=trump", "PATCH", http.StatusOK},
@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+
+	"github.com/prest/prest/adapters"
+	"github.com/prest/prest/config"
+	"github.com/prest/prest/renderers"
+	"github.com/structy/log"
+)
+
+// streamBufferSize is the size of the chunks written to the client
+const streamBufferSize = 32 << 10
+
+// streamFormats maps the formats of `_stream` to their content type
+var streamFormats = map[string]string{
+	"json":   "application/json",
+	"ndjson": "application/x-ndjson",
+}
+
+// streamFormat returns the format of a streamed response, `_stream=true`
+// streams a json array
+func streamFormat(r *http.Request) (format string, ok bool) {
+	format = r.URL.Query().Get("_stream")
+	if format == "" {
+		return "", true
+	}
+	if format == "true" {
+		format = "json"
+	}
+	_, ok = streamFormats[format]
+	return
+}
+
+// streamRows writes the rows of a query to the client as they are read
+// from the database, as a json array or as one json object per line
+// (ndjson). Writing blocks while the client does not read, which holds
+// the reading of the rows. The error is returned when nothing was written
+// yet, the connection is closed otherwise
+func streamRows(ctx context.Context, w http.ResponseWriter, format, SQL string, values []interface{}) (err error) {
+	buf := bufio.NewWriterSize(w, streamBufferSize)
+	started := false
+	start := func() {
+		w.Header().Set("Content-Type", streamFormats[format])
+		w.WriteHeader(http.StatusOK)
+		if format == "json" {
+			buf.WriteByte('[')
+		}
+		started = true
+	}
+	err = config.PrestConf.Adapter.QueryStreamCtx(ctx, SQL, func(row []byte) (err error) {
+		if !started {
+			start()
+		} else if format == "json" {
+			buf.WriteByte(',')
+		}
+		if _, err = buf.Write(row); err != nil {
+			return
+		}
+		if format == "ndjson" {
+			err = buf.WriteByte('\n')
+		}
+		return
+	}, values...)
+	if err != nil {
+		if !started {
+			return
+		}
+		log.Errorln("could not stream rows:", err)
+		abortResponse(w)
+		return nil
+	}
+	if !started {
+		start()
+	}
+	if format == "json" {
+		buf.WriteByte(']')
+	}
+	if flushErr := buf.Flush(); flushErr != nil {
+		log.Errorln("could not stream rows:", flushErr)
+	}
+	return
+}
+
+// streamTabular writes the rows of a query as csv or tsv, with a header
+// row of the columns in the order of the query, as they are read from the
+// database. The response is downloaded as a file named after name. The
+// error is returned when nothing was written yet, the connection is
+// closed otherwise
+func streamTabular(ctx context.Context, w http.ResponseWriter, format, name, SQL string, values []interface{}) (err error) {
+	buf := bufio.NewWriterSize(w, streamBufferSize)
+	tw := renderers.NewTabularWriter(buf, format)
+	started := false
+	err = config.PrestConf.Adapter.QueryRowsCtx(ctx, SQL, func(row []string) error {
+		if !started {
+			w.Header().Set("Content-Type", renderers.ContentType(format))
+			w.Header().Set("Content-Disposition", renderers.Attachment(name, format))
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		return tw.Write(row)
+	}, values...)
+	if err != nil {
+		if !started {
+			return
+		}
+		log.Errorln("could not stream rows:", err)
+		abortResponse(w)
+		return nil
+	}
+	tw.Flush()
+	if flushErr := tw.Error(); flushErr != nil {
+		log.Errorln("could not stream rows:", flushErr)
+	} else if flushErr = buf.Flush(); flushErr != nil {
+		log.Errorln("could not stream rows:", flushErr)
+	}
+	return
+}
+
+// streamColumnar writes the rows of a query as an arrow stream or a
+// parquet file typed by the columns of the result, in batches of rows as
+// they are read from the database. The response is downloaded as a file
+// named after name. The error is returned when nothing was written yet,
+// the connection is closed otherwise
+func streamColumnar(ctx context.Context, w http.ResponseWriter, format, name, SQL string, values []interface{}) (err error) {
+	buf := bufio.NewWriterSize(w, streamBufferSize)
+	var cw *renderers.ColumnarWriter
+	err = config.PrestConf.Adapter.QueryValuesCtx(ctx, SQL, func(columns []adapters.Column) (err error) {
+		if cw, err = renderers.NewColumnarWriter(buf, format, columns); err != nil {
+			return
+		}
+		w.Header().Set("Content-Type", renderers.ContentType(format))
+		w.Header().Set("Content-Disposition", renderers.Attachment(name, format))
+		w.WriteHeader(http.StatusOK)
+		return
+	}, func(row []interface{}) error {
+		return cw.Write(row)
+	}, values...)
+	if err == nil {
+		err = cw.Close()
+	}
+	if err != nil {
+		if cw == nil {
+			return
+		}
+		log.Errorln("could not stream rows:", err)
+		abortResponse(w)
+		return nil
+	}
+	if flushErr := buf.Flush(); flushErr != nil {
+		log.Errorln("could not stream rows:", flushErr)
+	}
+	return
+}
+
+// streamDownload writes the rows of a query in a format written as the
+// rows are read, csv, tsv, arrow or parquet
+func streamDownload(ctx context.Context, w http.ResponseWriter, format, name, SQL string, values []interface{}) error {
+	if renderers.IsColumnar(format) {
+		return streamColumnar(ctx, w, format, name, SQL, values)
+	}
+	return streamTabular(ctx, w, format, name, SQL, values)
+}
+
+// abortResponse closes the connection of a response that failed after its
+// status was sent, so that the client does not take it as complete
+func abortResponse(w http.ResponseWriter) {
+	conn, _, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+	conn.Close()
+}
@@ -1,18 +1,24 @@
 package controllers
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/prest/prest/adapters"
+	"github.com/prest/prest/adapters/postgres"
 	"github.com/prest/prest/cache"
 	"github.com/prest/prest/config"
 	pctx "github.com/prest/prest/context"
+	"github.com/prest/prest/renderers"
 	"github.com/structy/log"
 )
 
@@ -128,6 +134,45 @@ func SelectFromTables(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	stream, ok := streamFormat(r)
+	if !ok {
+		err := fmt.Errorf("invalid stream format: %v", queries.Get("_stream"))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if stream != "" && (queries.Has("_after") || queries.Has("_before")) {
+		err := errors.New("_stream can not be used with keyset pagination")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	format, _ := renderers.Format(r)
+	download := renderers.IsTabular(format) || renderers.IsColumnar(format)
+	if renderers.Streamed(format) && (queries.Has("_after") || queries.Has("_before")) {
+		err := fmt.Errorf("%s can not be used with keyset pagination", format)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// the other formats are rendered from the json array of the rows
+	if stream == renderers.NDJSON && format != renderers.JSON && !renderers.Streamed(format) {
+		err := fmt.Errorf("_stream=ndjson can not be rendered as %s", format)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if format == renderers.NDJSON {
+		stream = format
+	}
+	// rows addressed by primary key are single objects
+	_, byKey := vars["pk"]
+	single := queries.Get("_single") == "true" || format == renderers.Object || byKey
+	if single && (stream != "" || download) {
+		err := fmt.Errorf("a single object can not be rendered as %s", format)
+		if stream != "" {
+			err = errors.New("_single can not be used with _stream")
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// get selected columns, "*" if empty "_columns"
 	cols, err := config.PrestConf.Adapter.FieldsPermissions(r, table, "read")
 	if err != nil {
@@ -141,11 +186,99 @@ func SelectFromTables(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	selectStr, err := config.PrestConf.Adapter.SelectFields(cols)
+	// sql query formatting if there is a count rule
+	countQuery, err := config.PrestConf.Adapter.CountByRequest(r)
+	if err != nil {
+		err = fmt.Errorf("could not perform CountByRequest: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// the values of the select come first, none when it is replaced by the
+	// count rule
+	selectStr, selectValues, err := config.PrestConf.Adapter.SelectFields(cols, 1)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if countQuery != "" {
+		selectValues = nil
+	}
+
+	ctx := context.WithValue(r.Context(), pctx.DBNameKey, database)
+
+	timeout, _ := ctx.Value(pctx.HTTPTimeoutKey).(int)
+	ctx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(timeout))
+	defer cancel()
+
+	// sql query formatting if there is a where rule
+	requestWhere, values, err := whereByRequest(ctx, r, schema, table, len(selectValues)+1)
+	if err != nil {
+		err = fmt.Errorf("could not perform WhereByRequest: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	values = append(selectValues, values...)
+
+	// sql query formatting if there is a time bucket rule
+	bucketField, bucketSQL, bucketValues, err := config.PrestConf.Adapter.BucketByRequest(ctx, r, len(values)+1)
+	if err != nil {
+		err = fmt.Errorf("could not perform BucketByRequest: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if bucketSQL != "" {
+		// counts the rows of each bucket when no field is selected
+		if queries.Get("_select") == "" {
+			selectStr, _, _ = config.PrestConf.Adapter.SelectFields([]string{"count:*:count"}, 1)
+		}
+		selectStr = bucketSelect(selectStr, bucketField, bucketSQL)
+		values = append(values, bucketValues...)
+	}
+	filter, filterValues := requestWhere, values
+
+	// sql query formatting if there is a keyset (cursor) pagination rule
+	keyset, keysetValues, err := config.PrestConf.Adapter.KeysetByRequest(r, len(values)+1)
+	if err != nil {
+		err = fmt.Errorf("could not perform KeysetByRequest: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if keyset != "" {
+		if requestWhere != "" {
+			requestWhere = fmt.Sprintf("(%s) AND %s", requestWhere, keyset)
+		} else {
+			requestWhere = keyset
+		}
+		values = append(values, keysetValues...)
+	}
+
+	// sql query formatting if there is a full text search rank or headline
+	// rule, ignored when counting
+	var rank string
+	var headlines []string
+	if queries.Get("_count") == "" {
+		var textSearchValues []interface{}
+		rank, headlines, textSearchValues, err = config.PrestConf.Adapter.TextSearchByRequest(r, len(values)+1)
+		if err != nil {
+			err = fmt.Errorf("could not perform TextSearchByRequest: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		values = append(values, textSearchValues...)
+	}
+
+	// sql query formatting if there is an expand (embedded resources) rule
+	expand, err := config.PrestConf.Adapter.ExpandByRequest(ctx, r, schema, table)
+	if err != nil {
+		err = fmt.Errorf("could not perform ExpandByRequest: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	countSelect := selectStr
+	if extra := append(headlines, expand...); len(extra) > 0 {
+		selectStr = fmt.Sprintf("%s, %s FROM", strings.TrimSuffix(selectStr, " FROM"), strings.Join(extra, ", "))
+	}
 	query := config.PrestConf.Adapter.SelectSQL(selectStr, database, schema, table)
 
 	// sql query formatting if there is a distinct rule
@@ -159,16 +292,12 @@ func SelectFromTables(w http.ResponseWriter, r *http.Request) {
 		query = strings.Replace(query, "SELECT", distinct, 1)
 	}
 
-	// sql query formatting if there is a count rule
-	countQuery, err := config.PrestConf.Adapter.CountByRequest(r)
-	if err != nil {
-		err = fmt.Errorf("could not perform CountByRequest: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
 	// _count_first: query string
 	countFirst := false
 	if countQuery != "" {
+		if bucketSQL != "" {
+			countQuery = bucketSelect(countQuery, bucketField, bucketSQL)
+		}
 		query = config.PrestConf.Adapter.SelectSQL(countQuery, database, schema, table)
 		// count returns a list, passing this parameter will return the first
 		// record as a non-list object
@@ -189,13 +318,39 @@ func SelectFromTables(w http.ResponseWriter, r *http.Request) {
 		query = fmt.Sprint(query, j)
 	}
 
-	// sql query formatting if there is a where rule
-	requestWhere, values, err := config.PrestConf.Adapter.WhereByRequest(r, 1)
-	if err != nil {
-		err = fmt.Errorf("could not perform WhereByRequest: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+	// sql query formatting if there is a groupby rule
+	groupBySQL, groupByValues := config.PrestConf.Adapter.GroupByClause(r, len(values)+1)
+	values = append(values, groupByValues...)
+	if bucketSQL != "" {
+		groupBySQL = bucketGroupBy(groupBySQL, bucketSQL)
+	}
+
+	// the total count (Prefer: count=) reads the rows matched by the
+	// filters, without the keyset, order and pagination rules
+	count := preference(r, "count")
+	var countSQL string
+	var countValues []interface{}
+	if count != "" && count != "none" && countQuery == "" {
+		countSQL = config.PrestConf.Adapter.SelectSQL(countSelect, database, schema, table)
+		if distinct != "" {
+			countSQL = strings.Replace(countSQL, "SELECT", distinct, 1)
+		}
+		countSQL = fmt.Sprint(countSQL, strings.Join(joinValues, ""))
+		if filter != "" {
+			countSQL = fmt.Sprint(countSQL, " WHERE ", filter)
+		}
+		countValues = append(countValues, filterValues...)
+		// the having values follow the filter values
+		countGroupBySQL, countGroupByValues := config.PrestConf.Adapter.GroupByClause(r, len(countValues)+1)
+		if bucketSQL != "" {
+			countGroupBySQL = bucketGroupBy(countGroupBySQL, bucketSQL)
+		}
+		if countGroupBySQL != "" {
+			countSQL = fmt.Sprintf("%s %s", countSQL, countGroupBySQL)
+			countValues = append(countValues, countGroupByValues...)
+		}
 	}
+
 	sqlSelect := query
 	if requestWhere != "" {
 		sqlSelect = fmt.Sprint(
@@ -204,8 +359,6 @@ func SelectFromTables(w http.ResponseWriter, r *http.Request) {
 			requestWhere)
 	}
 
-	// sql query formatting if there is a groupby rule
-	groupBySQL := config.PrestConf.Adapter.GroupByClause(r)
 	if groupBySQL != "" {
 		sqlSelect = fmt.Sprintf("%s %s", sqlSelect, groupBySQL)
 	}
@@ -217,6 +370,16 @@ func SelectFromTables(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if order == "" && bucketSQL != "" {
+		order = fmt.Sprintf(` ORDER BY "%s"`, bucketField)
+	}
+	if rank != "" {
+		if order != "" {
+			order = strings.Replace(order, "ORDER BY ", fmt.Sprintf("ORDER BY %s, ", rank), 1)
+		} else {
+			order = fmt.Sprint(" ORDER BY ", rank)
+		}
+	}
 	if order != "" {
 		sqlSelect = fmt.Sprintf("%s %s", sqlSelect, order)
 	}
@@ -228,21 +391,38 @@ func SelectFromTables(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	// two rows tell a single row from several
+	if single && page == "" {
+		page = "LIMIT 2"
+	}
 	sqlSelect = fmt.Sprint(sqlSelect, " ", page)
 
-	ctx := context.WithValue(r.Context(), pctx.DBNameKey, database)
-
-	timeout, _ := ctx.Value(pctx.HTTPTimeoutKey).(int)
-	ctx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(timeout))
-	defer cancel()
+	// sql query formatting if there is a gap-fill rule
+	sqlSelect, gapFillValues, err := config.PrestConf.Adapter.GapFillByRequest(r, sqlSelect, len(values)+1)
+	if err != nil {
+		err = fmt.Errorf("could not perform GapFillByRequest: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	values = append(values, gapFillValues...)
 
 	runQuery := config.PrestConf.Adapter.QueryCtx
 	// QueryCount returns the first record of the postgresql return as a non-list object
 	if countFirst {
 		runQuery = config.PrestConf.Adapter.QueryCountCtx
 	}
-	sc := runQuery(ctx, sqlSelect, values...)
-	if err = sc.Err(); err != nil {
+	var sc adapters.Scanner
+	switch {
+	case download:
+		// the rows are written as they are read, the total count is not sent
+		err = streamDownload(ctx, w, format, table, sqlSelect, values)
+	case stream != "":
+		err = streamRows(ctx, w, stream, sqlSelect, values)
+	default:
+		sc = runQuery(ctx, sqlSelect, values...)
+		err = sc.Err()
+	}
+	if err != nil {
 		if strings.Contains(err.Error(), fmt.Sprintf(`pq: relation "%s.%s" does not exist`, schema, table)) {
 			log.Println(err.Error())
 			http.Error(w, err.Error(), http.StatusNotFound)
@@ -251,12 +431,224 @@ func SelectFromTables(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if download || stream != "" {
+		return
+	}
+
+	body := sc.Bytes()
+	if queries.Has("_after") || queries.Has("_before") {
+		var next, prev string
+		body, next, prev, err = config.PrestConf.Adapter.KeysetPage(r, body)
+		if err != nil {
+			err = fmt.Errorf("could not perform KeysetPage: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if next != "" {
+			w.Header().Set("X-Next-Cursor", next)
+		}
+		if prev != "" {
+			w.Header().Set("X-Prev-Cursor", prev)
+		}
+	}
+
+	if countSQL != "" {
+		filtered := filter != "" || len(joinValues) > 0 || groupBySQL != "" || distinct != ""
+		err = setTotalCount(ctx, w, r, count, schema, table, countSQL, filtered, countValues, body)
+		if err != nil {
+			err = fmt.Errorf("could not perform TotalCount: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if single {
+		body, err = renderers.SingleObject(body)
+		if err != nil {
+			http.Error(w, err.Error(), singleObjectStatus(err))
+			return
+		}
+	}
+
+	// the ETag of a row route is the one If-Match compares on writes, it is
+	// not sent when the body is not the row as read from the table
+	etagged := !byKey || len(headlines) == 0 && len(expand) == 0 && len(joinValues) == 0 && groupBySQL == "" && bucketSQL == "" && countQuery == "" && plainColumns(cols)
+
+	// Cache arrow if enabled, objects negotiated by Accept and the
+	// responses to a Prefer header are not cached since the key is the URL,
+	// nor the reads of a transaction and the keyset pages whose cursors are
+	// sent as headers. The cached bodies are served with their ETag
+	cached := format != renderers.Object && len(r.Header.Values("Prefer")) == 0 && r.Context().Value(pctx.TxKey) == nil
+	if cached && etagged && !queries.Has("_after") && !queries.Has("_before") {
+		cache.BuntSet(r.URL.String(), string(body))
+	}
+	// the other formats are rendered from the body, they have no ETag
+	if (format == renderers.JSON || format == renderers.Object) && etagged {
+		etag := cache.ETag(body)
+		w.Header().Set("ETag", etag)
+		if !cache.NoneMatch(r, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	w.Write(body)
+}
+
+// plainColumns tells whether the selected columns are table columns, no
+// functions, aliases or json paths
+func plainColumns(cols []string) bool {
+	if len(cols) == 1 && cols[0] == "*" {
+		return true
+	}
+	for _, col := range cols {
+		if strings.ContainsAny(col, ".:()*[]-> ") {
+			return false
+		}
+	}
+	return true
+}
+
+// singleObjectStatus returns the status of the error of a single object
+// response, 404 when there are no rows and 406 when there are several
+func singleObjectStatus(err error) int {
+	switch {
+	case errors.Is(err, renderers.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, renderers.ErrNotAcceptable):
+		return http.StatusNotAcceptable
+	}
+	return http.StatusBadRequest
+}
+
+// bulkUpdateTable updates each row of the array of the body, matched by
+// its primary key, with its own values in one transaction
+func bulkUpdateTable(w http.ResponseWriter, r *http.Request, database, schema, table string) {
+	if _, byKey := mux.Vars(r)["pk"]; byKey {
+		err := errors.New("a bulk update can not be sent to the route of a row")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if r.Header.Get("If-Match") != "" {
+		http.Error(w, errIfMatchWithoutKey.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rows, err := config.PrestConf.Adapter.ParseBulkUpdateRequest(r)
+	if err != nil {
+		err = fmt.Errorf("could not perform UPDATE: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	// Cache arrow if enabled
-	cache.BuntSet(r.URL.String(), string(sc.Bytes()))
+	ctx := context.WithValue(r.Context(), pctx.DBNameKey, database)
+
+	timeout, _ := ctx.Value(pctx.HTTPTimeoutKey).(int)
+	ctx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(timeout))
+	defer cancel()
+
+	// the rows are the first placeholder
+	where, whereValues, err := whereByRequest(ctx, r, schema, table, 2)
+	if err != nil {
+		err = fmt.Errorf("could not perform WhereByRequest: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	returningSyntax, err := config.PrestConf.Adapter.ReturningByRequest(r)
+	if err != nil {
+		err = fmt.Errorf("could not perform ReturningByRequest: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sc := config.PrestConf.Adapter.BulkUpdateCtx(ctx, database, schema, table, rows, where, whereValues, returningSyntax)
+	if err = sc.Err(); err != nil {
+		if strings.Contains(err.Error(), fmt.Sprintf(`pq: relation "%s.%s" does not exist`, schema, table)) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	w.Write(sc.Bytes())
 }
 
+// whereByRequest returns the where syntax of the filters of the request
+// and, on the routes of a row, of its primary key. The soft deleted rows
+// are excluded unless `_with_deleted=true` is sent
+func whereByRequest(ctx context.Context, r *http.Request, schema, table string, initialPlaceholderID int) (where string, values []interface{}, err error) {
+	where, values, err = config.PrestConf.Adapter.WhereByRequest(r, initialPlaceholderID)
+	if err != nil {
+		return
+	}
+	withDeleted, err := config.PrestConf.Adapter.WithDeletedByRequest(r, table)
+	if err != nil {
+		return
+	}
+	if notDeleted := config.PrestConf.Adapter.SoftDeleteWhere(table); notDeleted != "" && !withDeleted {
+		where = andWhere(notDeleted, where)
+	}
+	pk, ok := mux.Vars(r)["pk"]
+	if !ok {
+		return
+	}
+	pkWhere, pkValues, err := config.PrestConf.Adapter.PrimaryKeyWhereCtx(ctx, schema, table, pk, initialPlaceholderID+len(values))
+	if err != nil {
+		return
+	}
+	return andWhere(pkWhere, where), append(values, pkValues...), nil
+}
+
+// andWhere joins two where syntaxes, either may be empty
+func andWhere(where, other string) string {
+	switch {
+	case where == "":
+		return other
+	case other == "":
+		return where
+	}
+	return fmt.Sprintf("%s AND %s", where, other)
+}
+
+// writeRows writes the result of an update or a delete, on the routes of
+// a row the returned row is written as an object and no row matched is
+// 404, or 412 when If-Match was sent
+func writeRows(w http.ResponseWriter, r *http.Request, body []byte) {
+	if _, byKey := mux.Vars(r)["pk"]; !byKey {
+		w.Write(body)
+		return
+	}
+	noRow := func() {
+		if r.Header.Get("If-Match") != "" {
+			http.Error(w, "the row changed or does not exist", http.StatusPreconditionFailed)
+			return
+		}
+		http.Error(w, "no row for the primary key", http.StatusNotFound)
+	}
+	var affected struct {
+		RowsAffected *int64 `json:"rows_affected"`
+	}
+	if json.Unmarshal(body, &affected) == nil && affected.RowsAffected != nil {
+		if *affected.RowsAffected == 0 {
+			noRow()
+			return
+		}
+		w.Write(body)
+		return
+	}
+	// the rows returned, null when there are none
+	if rows := strings.TrimSpace(string(body)); rows == "null" || rows == "[]" {
+		noRow()
+		return
+	}
+	body, err := renderers.SingleObject(body)
+	if err != nil {
+		http.Error(w, err.Error(), singleObjectStatus(err))
+		return
+	}
+	w.Write(body)
+}
+
 // InsertInTables perform insert in specific table
 func InsertInTables(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -277,8 +669,6 @@ func InsertInTables(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sql := config.PrestConf.Adapter.InsertSQL(database, schema, table, names, placeholders)
-
 	// set db name on ctx
 	ctx := context.WithValue(r.Context(), pctx.DBNameKey, database)
 
@@ -286,6 +676,18 @@ func InsertInTables(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(timeout))
 	defer cancel()
 
+	onConflict, err := config.PrestConf.Adapter.OnConflictByRequest(ctx, r, schema, table, preference(r, "resolution"), names)
+	if err != nil {
+		err = fmt.Errorf("could not perform OnConflictByRequest: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sql := config.PrestConf.Adapter.InsertSQL(database, schema, table, names, placeholders)
+	if onConflict != "" {
+		sql = fmt.Sprint(sql, " ", onConflict)
+	}
+
 	sc := config.PrestConf.Adapter.InsertCtx(ctx, sql, values...)
 	if err = sc.Err(); err != nil {
 		if strings.Contains(err.Error(), fmt.Sprintf(`pq: relation "%s.%s" does not exist`, schema, table)) {
@@ -296,6 +698,11 @@ func InsertInTables(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	// the row was skipped on conflict (ignore-duplicates)
+	if len(sc.Bytes()) == 0 {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
 	w.WriteHeader(http.StatusCreated)
 	w.Write(sc.Bytes())
 }
@@ -327,12 +734,25 @@ func BatchInsertInTables(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(timeout))
 	defer cancel()
 
+	onConflict, err := config.PrestConf.Adapter.OnConflictByRequest(ctx, r, schema, table, preference(r, "resolution"), names)
+	if err != nil {
+		err = fmt.Errorf("could not perform OnConflictByRequest: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	var sc adapters.Scanner
 	method := r.Header.Get("Prest-Batch-Method")
-	if strings.ToLower(method) != "copy" {
+	switch {
+	case strings.ToLower(method) != "copy":
 		sql := config.PrestConf.Adapter.InsertSQL(database, schema, table, names, placeholders)
+		if onConflict != "" {
+			sql = fmt.Sprint(sql, " ", onConflict)
+		}
 		sc = config.PrestConf.Adapter.BatchInsertValuesCtx(ctx, sql, values...)
-	} else {
+	case onConflict != "":
+		sc = config.PrestConf.Adapter.BatchUpsertCopyCtx(ctx, database, schema, table, strings.Split(names, ","), onConflict, values...)
+	default:
 		sc = config.PrestConf.Adapter.BatchInsertCopyCtx(ctx, database, schema, table, strings.Split(names, ","), values...)
 	}
 	if err = sc.Err(); err != nil {
@@ -361,14 +781,33 @@ func DeleteFromTable(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	where, values, err := config.PrestConf.Adapter.WhereByRequest(r, 1)
+	ctx := context.WithValue(r.Context(), pctx.DBNameKey, database)
+
+	timeout, _ := ctx.Value(pctx.HTTPTimeoutKey).(int)
+	ctx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(timeout))
+	defer cancel()
+
+	where, values, err := whereByRequest(ctx, r, schema, table, 1)
 	if err != nil {
 		err = fmt.Errorf("could not perform WhereByRequest: %v", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	ifMatch, ifMatchValues, err := ifMatchWhere(r, table, len(values)+1)
+	if err != nil {
+		err = fmt.Errorf("could not perform IfMatchByRequest: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	where = andWhere(where, ifMatch)
+	values = append(values, ifMatchValues...)
 
 	sql := config.PrestConf.Adapter.DeleteSQL(database, schema, table)
+	// tables with a soft_delete column set the timestamp of the rows
+	softDeleteSQL := config.PrestConf.Adapter.SoftDeleteSQL(database, schema, table)
+	if softDeleteSQL != "" {
+		sql = softDeleteSQL
+	}
 	if where != "" {
 		sql = fmt.Sprint(sql, " WHERE ", where)
 	}
@@ -387,13 +826,11 @@ func DeleteFromTable(w http.ResponseWriter, r *http.Request) {
 			returningSyntax)
 	}
 
-	ctx := context.WithValue(r.Context(), pctx.DBNameKey, database)
-
-	timeout, _ := ctx.Value(pctx.HTTPTimeoutKey).(int)
-	ctx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(timeout))
-	defer cancel()
-
-	sc := config.PrestConf.Adapter.DeleteCtx(ctx, sql, values...)
+	execute := config.PrestConf.Adapter.DeleteCtx
+	if softDeleteSQL != "" {
+		execute = config.PrestConf.Adapter.UpdateCtx
+	}
+	sc := execute(ctx, sql, values...)
 	if err = sc.Err(); err != nil {
 		if strings.Contains(err.Error(), fmt.Sprintf(`pq: relation "%s.%s" does not exist`, schema, table)) {
 			log.Println(sc.Err().Error())
@@ -403,7 +840,7 @@ func DeleteFromTable(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	w.Write(sc.Bytes())
+	writeRows(w, r, sc.Bytes())
 }
 
 // UpdateTable perform update table
@@ -419,6 +856,20 @@ func UpdateTable(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// an array of rows keyed by primary key is a bulk update
+	if r.Method == http.MethodPatch {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		if bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) {
+			bulkUpdateTable(w, r, database, schema, table)
+			return
+		}
+	}
+
 	setSyntax, values, err := config.PrestConf.Adapter.SetByRequest(r, 1)
 	if err != nil {
 		err = fmt.Errorf("could not perform UPDATE: %v", err)
@@ -427,14 +878,28 @@ func UpdateTable(w http.ResponseWriter, r *http.Request) {
 	}
 	sql := config.PrestConf.Adapter.UpdateSQL(database, schema, table, setSyntax)
 
+	ctx := context.WithValue(r.Context(), pctx.DBNameKey, database)
+
+	timeout, _ := ctx.Value(pctx.HTTPTimeoutKey).(int)
+	ctx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(timeout))
+	defer cancel()
+
 	pid := len(values) + 1 // placeholder id
 
-	where, whereValues, err := config.PrestConf.Adapter.WhereByRequest(r, pid)
+	where, whereValues, err := whereByRequest(ctx, r, schema, table, pid)
 	if err != nil {
 		err = fmt.Errorf("could not perform WhereByRequest: %v", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	ifMatch, ifMatchValues, err := ifMatchWhere(r, table, pid+len(whereValues))
+	if err != nil {
+		err = fmt.Errorf("could not perform IfMatchByRequest: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	where = andWhere(where, ifMatch)
+	whereValues = append(whereValues, ifMatchValues...)
 
 	if where != "" {
 		sql = fmt.Sprint(
@@ -457,11 +922,6 @@ func UpdateTable(w http.ResponseWriter, r *http.Request) {
 			" RETURNING ",
 			returningSyntax)
 	}
-	ctx := context.WithValue(r.Context(), pctx.DBNameKey, database)
-
-	timeout, _ := ctx.Value(pctx.HTTPTimeoutKey).(int)
-	ctx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(timeout))
-	defer cancel()
 
 	sc := config.PrestConf.Adapter.UpdateCtx(ctx, sql, values...)
 	if err = sc.Err(); err != nil {
@@ -472,7 +932,7 @@ func UpdateTable(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	w.Write(sc.Bytes())
+	writeRows(w, r, sc.Bytes())
 }
 
 // ShowTable show information from table
@@ -495,6 +955,16 @@ func ShowTable(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(timeout))
 	defer cancel()
 
+	if detail := r.URL.Query().Get("detail"); detail != "" {
+		if detail != "full" {
+			err := fmt.Errorf("invalid detail: %s, use full", detail)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		showTableDetail(ctx, w, schema, table)
+		return
+	}
+
 	sc := config.PrestConf.Adapter.ShowTableCtx(ctx, schema, table)
 	if sc.Err() != nil {
 		errorMessage := fmt.Sprintf("error to execute query, schema error %s", sc.Err())
@@ -503,3 +973,118 @@ func ShowTable(w http.ResponseWriter, r *http.Request) {
 	}
 	w.Write(sc.Bytes())
 }
+
+// showTableDetail writes the document of the columns, constraints,
+// indexes and comments of a table, `?detail=full`
+func showTableDetail(ctx context.Context, w http.ResponseWriter, schema, table string) {
+	sc := config.PrestConf.Adapter.ShowTableDetailCtx(ctx, schema, table)
+	if err := sc.Err(); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, postgres.ErrTableNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Write(sc.Bytes())
+}
+
+// bucketSelect adds the time bucket as the first column of a select
+func bucketSelect(selectStr, field, bucketSQL string) string {
+	return strings.Replace(selectStr, "SELECT ", fmt.Sprintf(`SELECT %s AS "%s", `, bucketSQL, field), 1)
+}
+
+// bucketGroupBy adds the time bucket as the first field of a group by
+func bucketGroupBy(groupBySQL, bucketSQL string) string {
+	if groupBySQL == "" {
+		return fmt.Sprint("GROUP BY ", bucketSQL)
+	}
+	return strings.Replace(groupBySQL, "GROUP BY ", fmt.Sprintf("GROUP BY %s, ", bucketSQL), 1)
+}
+
+// preference returns the value of a preference sent on the Prefer header,
+// e.g. `Prefer: count=exact`
+func preference(r *http.Request, name string) (value string) {
+	for _, header := range r.Header.Values("Prefer") {
+		for _, pref := range strings.FieldsFunc(header, func(c rune) bool { return c == ',' || c == ';' }) {
+			k, v, _ := strings.Cut(strings.TrimSpace(pref), "=")
+			if strings.EqualFold(k, name) {
+				return strings.ToLower(strings.TrimSpace(v))
+			}
+		}
+	}
+	return
+}
+
+// setTotalCount sets the X-Total-Count and Content-Range headers of a page
+// of rows, counted the way requested on `Prefer: count=`:
+//
+//   - exact: counts the rows
+//   - planned: the estimate of the query planner
+//   - estimated: the table statistics when nothing filters the table,
+//     the estimate of the query planner otherwise
+func setTotalCount(ctx context.Context, w http.ResponseWriter, r *http.Request, count, schema, table, countSQL string, filtered bool, values []interface{}, body []byte) (err error) {
+	total := int64(-1)
+	switch count {
+	case "exact":
+		total, err = config.PrestConf.Adapter.TotalCountCtx(ctx, "exact", countSQL, values...)
+	case "estimated":
+		if !filtered {
+			total, err = config.PrestConf.Adapter.TableCountEstimateCtx(ctx, schema, table)
+			if err != nil {
+				log.Errorln(err)
+				total, err = -1, nil
+			}
+		}
+		if total < 0 {
+			total, err = config.PrestConf.Adapter.TotalCountCtx(ctx, "planned", countSQL, values...)
+		}
+	case "planned":
+		total, err = config.PrestConf.Adapter.TotalCountCtx(ctx, "planned", countSQL, values...)
+	default:
+		// unknown preferences are ignored
+		return
+	}
+	if err != nil {
+		return
+	}
+
+	var rows []json.RawMessage
+	if err = json.Unmarshal(body, &rows); err != nil {
+		return
+	}
+	contentRange := fmt.Sprintf("*/%d", total)
+	if offset, ok := pageOffset(r); ok && len(rows) > 0 {
+		contentRange = fmt.Sprintf("%d-%d/%d", offset, offset+len(rows)-1, total)
+	}
+	w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+	w.Header().Set("Content-Range", contentRange)
+	w.Header().Set("Preference-Applied", fmt.Sprint("count=", count))
+	return
+}
+
+// pageOffset returns the offset of the first row of the page, not known
+// on keyset pagination
+func pageOffset(r *http.Request) (offset int, ok bool) {
+	queries := r.URL.Query()
+	if queries.Has("_after") || queries.Has("_before") {
+		return
+	}
+	if queries.Get("_page") == "" {
+		return 0, true
+	}
+	page, err := strconv.Atoi(queries.Get("_page"))
+	if err != nil {
+		return
+	}
+	pageSize := postgres.DefaultPageSize
+	if queries.Get("_page_size") != "" {
+		if pageSize, err = strconv.Atoi(queries.Get("_page_size")); err != nil {
+			return
+		}
+	}
+	if page < 1 {
+		page = 1
+	}
+	return (page - 1) * pageSize, true
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/prest/prest/config"
 	pctx "github.com/prest/prest/context"
 	"github.com/prest/prest/testutils"
+	"github.com/stretchr/testify/require"
 )
 
 func init() {
@@ -105,11 +106,21 @@ func TestSelectFromTables(t *testing.T) {
 	}{
 		{"execute select in a table with array", "/prest-test/public/testarray", "GET", http.StatusOK, "[{\"id\": 100, \"data\": [\"Gohan\", \"Goten\"]}]"},
 		{"execute select in a table without custom where clause", "/prest-test/public/test", "GET", http.StatusOK, ""},
+		{"execute select in a table streaming a json array", "/prest-test/public/testarray?_stream=true", "GET", http.StatusOK, "[{\"id\": 100, \"data\": [\"Gohan\", \"Goten\"]}]"},
+		{"execute select in a table streaming ndjson", "/prest-test/public/testarray?_stream=ndjson", "GET", http.StatusOK, "{\"id\": 100, \"data\": [\"Gohan\", \"Goten\"]}\n"},
+		{"execute select in a table as a single object", "/prest-test/public/testarray?_single=true", "GET", http.StatusOK, "{\"id\": 100, \"data\": [\"Gohan\", \"Goten\"]}"},
+		{"execute select in a table as a single object without rows", "/prest-test/public/testarray?id=$eq.0&_single=true", "GET", http.StatusNotFound, ""},
+		{"execute select in a table as a single object with several rows", "/prest-test/public/test?_single=true", "GET", http.StatusNotAcceptable, ""},
+		{"execute select in a table streaming a single object", "/prest-test/public/test?_single=true&_stream=true", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table as csv", "/prest-test/public/testarray?_renderer=csv", "GET", http.StatusOK, "id,data\n100,\"{Gohan,Goten}\"\n"},
+		{"execute select in a table as parquet", "/prest-test/public/testarray?_renderer=parquet", "GET", http.StatusOK, ""},
+		{"execute select in a table as an arrow stream", "/prest-test/public/testarray?_renderer=arrow", "GET", http.StatusOK, ""},
+		{"execute select in a table as tsv", "/prest-test/public/testarray?_renderer=tsv&_select=data,id", "GET", http.StatusOK, "data\tid\n{Gohan,Goten}\t100\n"},
 		{"execute select in a table case sentive", "/prest-test/public/Reply", "GET", http.StatusOK, "[{\"id\": 1, \"name\": \"prest tester\"}, {\"id\": 2, \"name\": \"prest-test-insert\"}, {\"id\": 3, \"name\": \"prest-test-insert-ctx\"}, {\"id\": 4, \"name\": \"3prest-test-batch-insert\"}, {\"id\": 5, \"name\": \"3batch-prest-test-insert\"}, {\"id\": 6, \"name\": \"3prest-test-batch-insert-ctx\"}, {\"id\": 7, \"name\": \"3batch-prest-test-insert-ctx\"}, {\"id\": 8, \"name\": \"copy-ctx\"}, {\"id\": 9, \"name\": \"copy-ctx\"}, {\"id\": 10, \"name\": \"copy\"}, {\"id\": 11, \"name\": \"copy\"}]"},
 		{"execute select in a table with count all fields *", "/prest-test/public/test?_count=*", "GET", http.StatusOK, ""},
 		{"execute select in a table with count function", "/prest-test/public/test?_count=name", "GET", http.StatusOK, ""},
 		{"execute select in a table with custom where clause", "/prest-test/public/test?name=$eq.test", "GET", http.StatusOK, ""},
-		{"execute select in a table with custom join clause", "/prest-test/public/test?_join=inner:test8:test8.nameforjoin:$eq:test.name", "GET", http.StatusOK, ""},
+		{"execute select in a table with custom join clause", "/prest-test/public/test_list_only_id?_join=inner:test2:test2.id:$eq:test_list_only_id.id", "GET", http.StatusOK, ""},
 		{"execute select in a table with order clause empty", "/prest-test/public/test?_order=", "GET", http.StatusOK, ""},
 		{"execute select in a table with custom where clause and pagination", "/prest-test/public/test?name=$eq.test&_page=1&_page_size=20", "GET", http.StatusOK, ""},
 		{"execute select in a table with select fields", "/prest-test/public/test5?_select=celphone,name", "GET", http.StatusOK, ""},
@@ -127,10 +138,15 @@ func TestSelectFromTables(t *testing.T) {
 		{"execute select in a view with order function", "/prest-test/public/view_test?_order=-player", "GET", http.StatusOK, ""},
 		{"execute select in a view with custom where clause", "/prest-test/public/view_test?player=$eq.gopher", "GET", http.StatusOK, ""},
 		{"execute select in a view with custom join clause", "/prest-test/public/view_test?_join=inner:test2:test2.name:eq:view_test.player", "GET", http.StatusOK, ""},
+		{"execute select in a view with chained join clauses", "/prest-test/public/view_test?_join=inner:test2:t2:t2.name:$eq:view_test.player&_join=left:test3:t3:t3.name:$eq:t2.name", "GET", http.StatusOK, ""},
 		{"execute select in a view with custom where clause and pagination", "/prest-test/public/view_test?player=$eq.gopher&_page=1&_page_size=20", "GET", http.StatusOK, ""},
 		{"execute select in a view with select fields", "/prest-test/public/view_test?_select=player", "GET", http.StatusOK, ""},
 
+		{"execute select in a table with invalid stream format", "/prest-test/public/test?_stream=xml", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table streaming with keyset pagination", "/prest-test/public/test?_stream=true&_order=id&_after=", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table as csv with keyset pagination", "/prest-test/public/test?_renderer=csv&_order=id&_after=", "GET", http.StatusBadRequest, ""},
 		{"execute select in a table with invalid join clause", "/prest-test/public/test?_join=inner:test2:test2.name", "GET", http.StatusBadRequest, ""},
+		{"execute select in a table with join clause without permission", "/prest-test/public/test?_join=inner:test8:test8.nameforjoin:$eq:test.name", "GET", http.StatusBadRequest, ""},
 		{"execute select in a table with invalid where clause", "/prest-test/public/test?0name=$eq.test", "GET", http.StatusBadRequest, ""},
 		{"execute select in a table with order clause and column invalid", "/prest-test/public/test?_order=0name", "GET", http.StatusBadRequest, ""},
 		{"execute select in a table with invalid pagination clause", "/prest-test/public/test?name=$eq.test&_page=A", "GET", http.StatusBadRequest, ""},
@@ -163,6 +179,57 @@ func TestSelectFromTables(t *testing.T) {
 	}
 }
 
+func TestPreference(t *testing.T) {
+	var testCases = []struct {
+		description string
+		prefer      []string
+		value       string
+	}{
+		{"No Prefer header", nil, ""},
+		{"Single preference", []string{"count=exact"}, "exact"},
+		{"Preference list", []string{"return=representation, count=Planned"}, "planned"},
+		{"Repeated header", []string{"return=minimal", "count=estimated"}, "estimated"},
+		{"Other preferences", []string{"return=minimal"}, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/prest-test/public/test", nil)
+			for _, prefer := range tc.prefer {
+				r.Header.Add("Prefer", prefer)
+			}
+			if value := preference(r, "count"); value != tc.value {
+				t.Errorf("expected %q, got %q", tc.value, value)
+			}
+		})
+	}
+}
+
+func TestPageOffset(t *testing.T) {
+	var testCases = []struct {
+		description string
+		url         string
+		offset      int
+		ok          bool
+	}{
+		{"Without pagination", "/prest-test/public/test", 0, true},
+		{"Default page size", "/prest-test/public/test?_page=3", 20, true},
+		{"Page size", "/prest-test/public/test?_page=2&_page_size=5", 5, true},
+		{"Keyset pagination", "/prest-test/public/test?_after=abc", 0, false},
+		{"Invalid page", "/prest-test/public/test?_page=A", 0, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			offset, ok := pageOffset(r)
+			if offset != tc.offset || ok != tc.ok {
+				t.Errorf("expected %d %v, got %d %v", tc.offset, tc.ok, offset, ok)
+			}
+		})
+	}
+}
+
 func TestInsertInTables(t *testing.T) {
 	m := make(map[string]interface{})
 	m["name"] = "prest-test"
@@ -271,6 +338,61 @@ func TestBatchInsertInTables(t *testing.T) {
 	}
 }
 
+func TestUpsertInTables(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/{schema}/{table}", setHTTPTimeoutMiddleware(InsertInTables)).
+		Methods("POST")
+	router.HandleFunc("/batch/{database}/{schema}/{table}", setHTTPTimeoutMiddleware(BatchInsertInTables)).
+		Methods("POST")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	row := map[string]interface{}{"id": 9001, "name": "prest-upsert"}
+	updated := map[string]interface{}{"id": 9001, "name": "prest-upsert-updated"}
+	rows := []map[string]interface{}{{"id": 9002, "name": "prest-upsert-batch"}, {"id": 9003, "name": "prest-upsert-batch-2"}}
+
+	var testCases = []struct {
+		description string
+		url         string
+		request     interface{}
+		prefer      string
+		isCopy      bool
+		status      int
+	}{
+		{"execute upsert of a new row", "/prest-test/public/test4?_on_conflict=id", row, "", false, http.StatusCreated},
+		{"execute upsert of an existing row", "/prest-test/public/test4", updated, "resolution=merge-duplicates", false, http.StatusCreated},
+		{"execute upsert ignoring an existing row", "/prest-test/public/test4", updated, "resolution=ignore-duplicates", false, http.StatusOK},
+		{"execute upsert of chosen columns", "/prest-test/public/test4?_on_conflict=id&_on_conflict_update=name", row, "", false, http.StatusCreated},
+		{"execute upsert of a column not inserted", "/prest-test/public/test4?_on_conflict=id&_on_conflict_update=celphone", row, "", false, http.StatusBadRequest},
+		{"execute upsert with an invalid resolution", "/prest-test/public/test4", row, "resolution=replace", false, http.StatusBadRequest},
+		{"execute upsert with an invalid target", "/prest-test/public/test4?_on_conflict=0id", row, "", false, http.StatusBadRequest},
+		{"execute upsert in a table without primary key", "/prest-test/public/test", map[string]interface{}{"name": "prest"}, "resolution=merge-duplicates", false, http.StatusBadRequest},
+		{"execute batch upsert", "/batch/prest-test/public/test4?_on_conflict=id", rows, "", false, http.StatusCreated},
+		{"execute batch upsert again", "/batch/prest-test/public/test4", rows, "resolution=merge-duplicates", false, http.StatusCreated},
+		{"execute batch upsert with copy", "/batch/prest-test/public/test4?_on_conflict=id", rows, "", true, http.StatusCreated},
+		{"execute batch upsert ignoring with copy", "/batch/prest-test/public/test4", rows, "resolution=ignore-duplicates", true, http.StatusCreated},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			byt, err := json.Marshal(tc.request)
+			require.NoError(t, err)
+			req, err := http.NewRequest(http.MethodPost, server.URL+tc.url, bytes.NewReader(byt))
+			require.NoError(t, err)
+			if tc.prefer != "" {
+				req.Header.Set("Prefer", tc.prefer)
+			}
+			if tc.isCopy {
+				req.Header.Set("Prest-Batch-Method", "copy")
+			}
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			require.Equal(t, tc.status, resp.StatusCode)
+		})
+	}
+}
+
 func TestDeleteFromTable(t *testing.T) {
 	router := mux.NewRouter()
 	router.HandleFunc("/{database}/{schema}/{table}", setHTTPTimeoutMiddleware(DeleteFromTable)).
@@ -337,6 +459,73 @@ func TestUpdateFromTable(t *testing.T) {
 	}
 }
 
+func TestRowsByPrimaryKey(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/{schema}/{table}/{pk}", setHTTPTimeoutMiddleware(SelectFromTables)).
+		Methods("GET")
+	router.HandleFunc("/{database}/{schema}/{table}/{pk}", setHTTPTimeoutMiddleware(UpdateTable)).
+		Methods("PUT", "PATCH")
+	router.HandleFunc("/{database}/{schema}/{table}/{pk}", setHTTPTimeoutMiddleware(DeleteFromTable)).
+		Methods("DELETE")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	m := make(map[string]interface{})
+	m["name"] = "prest"
+
+	var testCases = []struct {
+		description string
+		url         string
+		method      string
+		request     map[string]interface{}
+		status      int
+	}{
+		{"execute select of a missing row", "/prest-test/public/test4/0", "GET", nil, http.StatusNotFound},
+		{"execute select of a row of a table without primary key", "/prest-test/public/test/1", "GET", nil, http.StatusBadRequest},
+		{"execute select of a row with an invalid key", "/prest-test/public/test4/a,b", "GET", nil, http.StatusBadRequest},
+		{"execute select of a row as csv", "/prest-test/public/test4/1?_renderer=csv", "GET", nil, http.StatusBadRequest},
+		{"execute update of a missing row", "/prest-test/public/test4/0", "PATCH", m, http.StatusNotFound},
+		{"execute update of a missing row returning it", "/prest-test/public/test4/0?_returning=*", "PUT", m, http.StatusNotFound},
+		{"execute delete of a missing row", "/prest-test/public/test4/0", "DELETE", nil, http.StatusNotFound},
+		{"execute delete of a row of a table without primary key", "/prest-test/public/test/1", "DELETE", nil, http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		testutils.DoRequest(t, server.URL+tc.url, tc.request, tc.method, tc.status, tc.description)
+	}
+}
+
+func TestBulkUpdateTable(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/{database}/{schema}/{table}", setHTTPTimeoutMiddleware(UpdateTable)).
+		Methods("PUT", "PATCH")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	rows := []map[string]interface{}{{"id": 1, "name": "prest-bulk-1"}, {"id": 2, "name": "prest-bulk-2"}}
+
+	var testCases = []struct {
+		description string
+		url         string
+		request     interface{}
+		status      int
+	}{
+		{"execute bulk update", "/prest-test/public/test4", rows, http.StatusOK},
+		{"execute bulk update returning the rows", "/prest-test/public/test4?_returning=*", rows, http.StatusOK},
+		{"execute bulk update with a filter", "/prest-test/public/test4?name=$eq.prest-bulk-1", rows, http.StatusOK},
+		{"execute bulk update of rows without key", "/prest-test/public/test4", []map[string]interface{}{{"name": "prest"}}, http.StatusBadRequest},
+		{"execute bulk update of rows setting nothing", "/prest-test/public/test4", []map[string]interface{}{{"id": 1}}, http.StatusBadRequest},
+		{"execute bulk update in a table without primary key", "/prest-test/public/test", rows, http.StatusBadRequest},
+		{"execute bulk update without rows", "/prest-test/public/test4", []map[string]interface{}{}, http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.description)
+		testutils.DoRequest(t, server.URL+tc.url, tc.request, "PATCH", tc.status, "UpdateTable")
+	}
+}
+
 func TestShowTable(t *testing.T) {
 	router := mux.NewRouter()
 	router.HandleFunc("/show/{database}/{schema}/{table}", setHTTPTimeoutMiddleware(ShowTable)).
@@ -353,12 +542,48 @@ func TestShowTable(t *testing.T) {
 		{"execute select in a table test custom information table", "/show/prest-test/public/test", "GET", http.StatusOK},
 		{"execute select in a table test2 custom information table", "/show/prest-test/public/test2", "GET", http.StatusOK},
 		{"execute select in a invalid db", "/show/invalid/public/test2", "GET", http.StatusBadRequest},
+		{"execute select of the full detail of a table", "/show/prest-test/public/test4?detail=full", "GET", http.StatusOK},
+		{"execute select of the full detail of a missing table", "/show/prest-test/public/missing_table?detail=full", "GET", http.StatusNotFound},
+		{"execute select of an invalid detail", "/show/prest-test/public/test4?detail=some", "GET", http.StatusBadRequest},
 	}
 
 	for _, tc := range testCases {
 		t.Log(tc.description)
 		testutils.DoRequest(t, server.URL+tc.url, nil, tc.method, tc.status, "ShowTable")
 	}
+
+	t.Run("full detail", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/show/prest-test/public/test4?detail=full")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var detail struct {
+			Kind    string `json:"kind"`
+			Columns []struct {
+				Name     string `json:"name"`
+				Type     string `json:"type"`
+				Nullable bool   `json:"nullable"`
+			} `json:"columns"`
+			PrimaryKey struct {
+				Columns []string `json:"columns"`
+			} `json:"primary_key"`
+			UniqueConstraints []struct {
+				Columns []string `json:"columns"`
+			} `json:"unique_constraints"`
+			Indexes []struct {
+				Unique bool `json:"unique"`
+			} `json:"indexes"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&detail))
+		require.Equal(t, "table", detail.Kind)
+		require.Len(t, detail.Columns, 2)
+		require.Equal(t, "id", detail.Columns[0].Name)
+		require.Equal(t, "integer", detail.Columns[0].Type)
+		require.False(t, detail.Columns[0].Nullable)
+		require.Equal(t, []string{"id"}, detail.PrimaryKey.Columns)
+		require.Len(t, detail.UniqueConstraints, 1)
+		require.Equal(t, []string{"name"}, detail.UniqueConstraints[0].Columns)
+		require.Len(t, detail.Indexes, 2)
+	})
 }
 
 func setHTTPTimeoutMiddleware(h http.HandlerFunc) http.HandlerFunc {
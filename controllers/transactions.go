@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prest/prest/config"
+	pctx "github.com/prest/prest/context"
+	"github.com/prest/prest/transactions"
+	"github.com/structy/log"
+)
+
+// OpenTransaction begins a transaction on the database of the path, or the
+// configured one, and returns its id. The requests carrying the id on the
+// Prest-Transaction header run in it until it is committed, rolled back
+// or idle for longer than the tx.timeout
+func OpenTransaction(w http.ResponseWriter, r *http.Request) {
+	database, ok := mux.Vars(r)["database"]
+	if !ok {
+		database = config.PrestConf.Adapter.GetDatabase()
+	}
+	if config.PrestConf.SingleDB && (config.PrestConf.Adapter.GetDatabase() != database) {
+		err := fmt.Errorf("database not registered: %v", database)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// checked before a connection is taken, and again once it is
+	if max := config.PrestConf.TxMax; max > 0 && transactions.Count() >= max {
+		http.Error(w, transactions.ErrTooMany.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	// the transaction outlives the request, it is not begun with its context
+	ctx := context.WithValue(context.Background(), pctx.DBNameKey, database)
+	tx, err := config.PrestConf.Adapter.GetTransactionCtx(ctx)
+	if err != nil {
+		err = fmt.Errorf("could not perform OpenTransaction: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	id, err := transactions.Open(tx, database, config.PrestConf.TxMax)
+	if err != nil {
+		if txerr := tx.Rollback(); txerr != nil {
+			log.Errorln(txerr)
+		}
+		status := http.StatusInternalServerError
+		if errors.Is(err, transactions.ErrTooMany) {
+			status = http.StatusServiceUnavailable
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	if config.PrestConf.TxTimeout > 0 {
+		transactions.StartReaper(time.Second * time.Duration(config.PrestConf.TxTimeout))
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"id":       id,
+		"database": database,
+		"timeout":  config.PrestConf.TxTimeout,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set(transactions.Header, id)
+	w.WriteHeader(http.StatusCreated)
+	w.Write(body)
+}
+
+// CommitTransaction commits the transaction of the id of the path, once
+// the request running in it is done
+func CommitTransaction(w http.ResponseWriter, r *http.Request) {
+	endTransaction(w, r, (*transactions.Transaction).Commit)
+}
+
+// RollbackTransaction rolls back the transaction of the id of the path,
+// once the request running in it is done
+func RollbackTransaction(w http.ResponseWriter, r *http.Request) {
+	endTransaction(w, r, (*transactions.Transaction).Rollback)
+}
+
+func endTransaction(w http.ResponseWriter, r *http.Request, end func(*transactions.Transaction) error) {
+	t, err := transactions.Acquire(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	defer t.Release()
+	if err = end(t); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prest/prest/middlewares"
+	"github.com/prest/prest/transactions"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/negroni/v3"
+)
+
+func TestTransactions(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/_tx", OpenTransaction).Methods("POST")
+	router.HandleFunc("/_tx/{id}/commit", CommitTransaction).Methods("POST")
+	router.HandleFunc("/_tx/{id}/rollback", RollbackTransaction).Methods("POST")
+	router.HandleFunc("/{database}/{schema}/{table}", setHTTPTimeoutMiddleware(SelectFromTables)).Methods("GET")
+	router.HandleFunc("/{database}/{schema}/{table}", setHTTPTimeoutMiddleware(InsertInTables)).Methods("POST")
+	router.HandleFunc("/batch/{database}/{schema}/{table}", setHTTPTimeoutMiddleware(BatchInsertInTables)).Methods("POST")
+	n := negroni.New(middlewares.TransactionMiddleware())
+	n.UseHandler(router)
+	server := httptest.NewServer(n)
+	defer server.Close()
+
+	do := func(method, url, tx string, body interface{}) *http.Response {
+		byt, err := json.Marshal(body)
+		require.NoError(t, err)
+		req, err := http.NewRequest(method, server.URL+url, bytes.NewReader(byt))
+		require.NoError(t, err)
+		if tx != "" {
+			req.Header.Set(transactions.Header, tx)
+		}
+		if strings.HasPrefix(url, "/batch/") {
+			req.Header.Set("Prest-Batch-Method", "copy")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+	open := func() string {
+		resp := do("POST", "/_tx", "", nil)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		var body struct {
+			ID string `json:"id"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		require.Equal(t, body.ID, resp.Header.Get(transactions.Header))
+		return body.ID
+	}
+	rows := func(tx, name string) (rows []map[string]interface{}) {
+		resp := do("GET", "/prest-test/public/test4?name=$eq."+name, tx, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&rows))
+		return
+	}
+
+	t.Run("commit", func(t *testing.T) {
+		id := open()
+		resp := do("POST", "/prest-test/public/test4", id, map[string]interface{}{"name": "prest-tx-commit"})
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		require.Len(t, rows(id, "prest-tx-commit"), 1)
+		require.Empty(t, rows("", "prest-tx-commit"))
+		require.Equal(t, http.StatusNoContent, do("POST", "/_tx/"+id+"/commit", "", nil).StatusCode)
+		require.Len(t, rows("", "prest-tx-commit"), 1)
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		id := open()
+		resp := do("POST", "/prest-test/public/test4", id, map[string]interface{}{"name": "prest-tx-rollback"})
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		require.Equal(t, http.StatusNoContent, do("POST", "/_tx/"+id+"/rollback", "", nil).StatusCode)
+		require.Empty(t, rows("", "prest-tx-rollback"))
+		require.Equal(t, http.StatusNotFound, do("POST", "/_tx/"+id+"/commit", "", nil).StatusCode)
+		require.Equal(t, http.StatusNotFound, do("GET", "/prest-test/public/test4", id, nil).StatusCode)
+	})
+
+	t.Run("upserts by copy", func(t *testing.T) {
+		id := open()
+		upsert := []map[string]interface{}{{"id": 9101, "name": "prest-tx-upsert"}}
+		for i := 0; i < 2; i++ {
+			resp := do("POST", "/batch/prest-test/public/test4?_on_conflict=id", id, upsert)
+			require.Equal(t, http.StatusCreated, resp.StatusCode)
+		}
+		require.Equal(t, http.StatusNoContent, do("POST", "/_tx/"+id+"/commit", "", nil).StatusCode)
+		require.Len(t, rows("", "prest-tx-upsert"), 1)
+	})
+
+	t.Run("other database", func(t *testing.T) {
+		id := open()
+		require.Equal(t, http.StatusBadRequest, do("GET", "/secondary-db/public/test4", id, nil).StatusCode)
+		require.Equal(t, http.StatusNoContent, do("POST", "/_tx/"+id+"/rollback", "", nil).StatusCode)
+	})
+}
@@ -6,6 +6,8 @@ import (
 
 	"github.com/prest/prest/cache"
 	"github.com/prest/prest/config"
+	pctx "github.com/prest/prest/context"
+	"github.com/prest/prest/renderers"
 	"github.com/urfave/negroni/v3"
 )
 
@@ -19,8 +21,19 @@ func CacheMiddleware() negroni.Handler {
 		}
 		// team will not be used when downloading information, second result ignored
 		cacheRule, _ := cache.EndpointRules(r.URL.Path)
-		if config.PrestConf.Cache.Enabled && r.Method == "GET" && !match && cacheRule {
-			if cache.BuntGet(r.URL.String(), w) {
+		// the reads of a transaction see its uncommitted rows
+		inTx := r.Context().Value(pctx.TxKey) != nil
+		// the key is the URL, the responses asking a total count by Prefer
+		// are not cached
+		prefer := len(r.Header.Values("Prefer")) > 0
+		if config.PrestConf.Cache.Enabled && r.Method == "GET" && !match && cacheRule && !inTx && !prefer {
+			// the other formats are rendered from the json body, they have
+			// no ETag
+			if format, _ := renderers.Format(r); format == renderers.JSON {
+				if cache.BuntGetETag(r.URL.String(), w, r) {
+					return
+				}
+			} else if cache.BuntGet(r.URL.String(), w) {
 				return
 			}
 		}
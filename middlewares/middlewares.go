@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"path"
 	"strconv"
 	"strings"
 	"time"
@@ -13,6 +14,7 @@ import (
 	"github.com/prest/prest/config"
 	pctx "github.com/prest/prest/context"
 	"github.com/prest/prest/controllers/auth"
+	"github.com/prest/prest/renderers"
 	"github.com/urfave/negroni/v3"
 	"gopkg.in/square/go-jose.v2/jwt"
 )
@@ -23,13 +25,34 @@ var (
 )
 
 // HandlerSet add content type header
+//
+// the format of the response is negotiated from `_renderer` and the
+// Accept header, 406 is returned when it is not supported. Streamed
+// responses (`_stream`) and the formats that table reads write as the
+// rows are read are passed through without buffering, only their errors
+// are rendered
 func HandlerSet() negroni.Handler {
 	return negroni.HandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
-		format := r.URL.Query().Get("_renderer")
+		w.Header().Add("Vary", "Accept")
+		name := path.Base(r.URL.Path)
+		format, err := renderers.Format(r)
+		if err != nil {
+			renderFormat(w, http.StatusNotAcceptable, []byte(err.Error()), renderers.JSON, name)
+			return
+		}
+		if r.URL.Query().Get("_stream") != "" || renderers.Streamed(format) {
+			sw := &streamWriter{ResponseWriter: w, format: format}
+			next(sw, r)
+			sw.render(name)
+			return
+		}
 		recorder := httptest.NewRecorder()
 		negroniResp := negroni.NewResponseWriter(recorder)
 		next(negroniResp, r)
-		renderFormat(w, recorder, format)
+		for key := range recorder.Header() {
+			w.Header().Set(key, recorder.Header().Get(key))
+		}
+		renderFormat(w, recorder.Code, recorder.Body.Bytes(), format, name)
 	})
 }
 
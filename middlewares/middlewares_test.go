@@ -9,6 +9,7 @@ import (
 	"github.com/prest/prest/config"
 	"github.com/prest/prest/controllers/auth"
 	"github.com/stretchr/testify/require"
+	"github.com/urfave/negroni/v3"
 	"gopkg.in/square/go-jose.v2"
 	"gopkg.in/square/go-jose.v2/jwt"
 )
@@ -126,3 +127,44 @@ func TestValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestHandlerSet(t *testing.T) {
+	n := negroni.New(HandlerSet())
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id": 1, "name": "prest"}]`))
+	})
+
+	var testCases = []struct {
+		description string
+		url         string
+		accept      string
+		status      int
+		contentType string
+		body        string
+	}{
+		{"Default", "/databases", "", http.StatusOK, "application/json", `[{"id": 1, "name": "prest"}]`},
+		{"Accept json", "/databases", "application/json", http.StatusOK, "application/json", `[{"id": 1, "name": "prest"}]`},
+		{"Accept xml", "/databases", "application/xml", http.StatusOK, "application/xml", "<objects><object><id>1</id><name>prest</name></object></objects>"},
+		{"Accept csv", "/databases", "text/csv", http.StatusOK, "text/csv", "id,name\n1,prest\n"},
+		{"Accept ndjson", "/databases", "application/x-ndjson", http.StatusOK, "application/x-ndjson", "{\"id\":1,\"name\":\"prest\"}\n"},
+		{"Accept msgpack", "/databases", "application/msgpack", http.StatusOK, "application/msgpack", "\x91\x82\xa2id\x01\xa4name\xa5prest"},
+		{"Accept single object", "/databases", "application/vnd.prest.object+json", http.StatusOK, "application/vnd.prest.object+json", `{"id": 1, "name": "prest"}`},
+		{"Renderer over accept", "/databases?_renderer=xml", "text/csv", http.StatusOK, "application/xml", "<objects><object><id>1</id><name>prest</name></object></objects>"},
+		{"Not acceptable", "/databases", "text/html", http.StatusNotAcceptable, "application/json", "{\n\t\"error\": \"unsupported media type text/html: not acceptable\"\n}"},
+		{"Columnar format from json", "/databases?_renderer=parquet", "", http.StatusNotAcceptable, "application/json", "{\n\t\"error\": \"only table reads and scripts are available as arrow or parquet: not acceptable\"\n}"},
+		{"Unknown renderer", "/databases?_renderer=yaml", "", http.StatusNotAcceptable, "application/json", "{\n\t\"error\": \"unsupported renderer yaml: not acceptable\"\n}"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			r.Header.Set("Accept", tc.accept)
+			recorder := httptest.NewRecorder()
+			n.ServeHTTP(recorder, r)
+			require.Equal(t, tc.status, recorder.Code)
+			require.Equal(t, tc.contentType, recorder.Header().Get("Content-Type"))
+			require.Equal(t, "Accept", recorder.Header().Get("Vary"))
+			require.Equal(t, tc.body, recorder.Body.String())
+		})
+	}
+}
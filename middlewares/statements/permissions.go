@@ -7,4 +7,8 @@ const (
 	WRITE string = "write"
 	// DELETE give delete permission
 	DELETE string = "delete"
+	// READDELETED give permission to read the soft deleted rows
+	READDELETED string = "read_deleted"
+	// EXECUTE give permission to call a function on /_RPC
+	EXECUTE string = "execute"
 )
@@ -0,0 +1,53 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	pctx "github.com/prest/prest/context"
+	"github.com/prest/prest/transactions"
+	"github.com/urfave/negroni/v3"
+)
+
+// TransactionMiddleware runs the requests of the table and function
+// routes carrying the id of an open transaction on the Prest-Transaction
+// header in it, one request at a time
+func TransactionMiddleware() negroni.Handler {
+	return negroni.HandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		id := r.Header.Get(transactions.Header)
+		// the routes of the transactions take the id on the path
+		if id == "" || strings.HasPrefix(r.URL.Path, "/_tx") {
+			next(w, r)
+			return
+		}
+		path := r.URL.Path
+		if strings.HasPrefix(path, "/batch/") {
+			path = strings.TrimPrefix(path, "/batch")
+		}
+		// functions take the database on the path as the tables
+		if strings.HasPrefix(path, "/_RPC/") {
+			path = strings.TrimPrefix(path, "/_RPC")
+		}
+		vars := getVars(path)
+		if vars == nil {
+			err := fmt.Errorf("%s is only supported on the table and function routes", transactions.Header)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		t, err := transactions.Acquire(id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		defer t.Release()
+		if vars["database"] != t.Database {
+			err = fmt.Errorf("transaction %s runs on database %s", id, t.Database)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ctx := context.WithValue(r.Context(), pctx.TxKey, t.Tx)
+		next(w, r.WithContext(ctx))
+	})
+}
@@ -1,22 +1,43 @@
 package middlewares
 
 import (
+	"bytes"
 	"encoding/json"
-	"fmt"
-	"io/ioutil"
+	"errors"
 	"net/http"
-	"net/http/httptest"
 	"regexp"
 	"strings"
 
-	"github.com/clbanning/mxj/j2x"
 	"github.com/prest/prest/config"
 	"github.com/prest/prest/middlewares/statements"
+	"github.com/prest/prest/renderers"
 )
 
+// reservedRoutes are the first segments of the routes that are not the
+// routes of a table
+var reservedRoutes = map[string]bool{
+	"show":     true,
+	"batch":    true,
+	"_QUERIES": true,
+	"_PLUGIN":  true,
+	"_tx":      true,
+	"_RPC":     true,
+}
+
 func getVars(path string) (paths map[string]string) {
+	// the routes of the transactions and of the functions are not the
+	// routes of a table
+	if strings.HasPrefix(path, "/_tx/") || strings.HasPrefix(path, "/_RPC/") {
+		return nil
+	}
 	pathList := strings.Split(path, "/")
 
+	// rows addressed by primary key, /{database}/{schema}/{table}/{pk}
+	if len(pathList) == 5 && pathList[0] == "" && !reservedRoutes[pathList[1]] {
+		paths = getVars(strings.Join(pathList[:4], "/"))
+		paths["pk"] = pathList[4]
+		return
+	}
 	if len(pathList) < 3 || len(pathList) > 4 {
 		return nil
 	} else if len(pathList) == 4 {
@@ -45,32 +66,100 @@ func permissionByMethod(method string) (permission string) {
 	return
 }
 
-func renderFormat(w http.ResponseWriter, recorder *httptest.ResponseRecorder, format string) {
-	for key := range recorder.Header() {
-		w.Header().Set(key, recorder.Header().Get(key))
-	}
-	byt, _ := ioutil.ReadAll(recorder.Body)
-	if recorder.Code >= 400 {
+// renderFormat writes a json response with the renderer of the requested
+// format, errors of tabular and columnar formats are rendered as json
+func renderFormat(w http.ResponseWriter, code int, byt []byte, format, name string) {
+	if code >= 400 {
 		m := make(map[string]string)
 		m["error"] = strings.TrimSpace(string(byt))
 		byt, _ = json.MarshalIndent(m, "", "\t")
+		if renderers.IsTabular(format) || renderers.IsColumnar(format) || format == renderers.Object {
+			format = renderers.JSON
+		}
 	}
-	switch format {
-	case "xml":
-		xmldata, err := j2x.JsonToXml(byt)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+	renderer, ok := renderers.Get(format)
+	if !ok {
+		format = renderers.JSON
+		renderer, _ = renderers.Get(format)
+	}
+	var buf bytes.Buffer
+	if len(bytes.TrimSpace(byt)) > 0 {
+		if err := renderer.Render(&buf, byt); err != nil {
+			code = http.StatusBadRequest
+			switch {
+			case errors.Is(err, renderers.ErrNotAcceptable):
+				code = http.StatusNotAcceptable
+			case errors.Is(err, renderers.ErrNotFound):
+				code = http.StatusNotFound
+			}
+			renderFormat(w, code, []byte(err.Error()), renderers.JSON, name)
 			return
 		}
-		xmlStr := fmt.Sprintf("<objects>%s</objects>", string(xmldata))
-		w.Header().Set("Content-Type", "application/xml")
-		w.WriteHeader(recorder.Code)
-		w.Write([]byte(xmlStr))
-	default:
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(recorder.Code)
-		w.Write(byt)
 	}
+	w.Header().Set("Content-Type", renderer.ContentType())
+	if renderers.IsTabular(format) {
+		w.Header().Set("Content-Disposition", renderers.Attachment(name, format))
+	}
+	w.WriteHeader(code)
+	w.Write(buf.Bytes())
+}
+
+// streamWriter passes a streamed response through to the client, error
+// responses are kept to be rendered as json. The responses of an other
+// format than json that the handler did not write as such are kept to be
+// converted
+type streamWriter struct {
+	http.ResponseWriter
+	format  string
+	code    int
+	started bool
+	body    bytes.Buffer
+}
+
+// WriteHeader sends the status of the responses passed through
+func (sw *streamWriter) WriteHeader(code int) {
+	if sw.started || sw.code != 0 {
+		return
+	}
+	if code < 400 && sw.passThrough() {
+		sw.started = true
+		sw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	sw.code = code
+}
+
+// passThrough returns true when the response is already in the requested
+// format
+func (sw *streamWriter) passThrough() bool {
+	if sw.format == renderers.JSON {
+		return true
+	}
+	return strings.HasPrefix(sw.Header().Get("Content-Type"), renderers.ContentType(sw.format))
+}
+
+// Write sends the body of the responses passed through
+func (sw *streamWriter) Write(b []byte) (int, error) {
+	if !sw.started && sw.code == 0 {
+		sw.WriteHeader(http.StatusOK)
+	}
+	if sw.started {
+		return sw.ResponseWriter.Write(b)
+	}
+	return sw.body.Write(b)
+}
+
+// Unwrap returns the writer of the client, used by http.ResponseController
+func (sw *streamWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
+
+// render writes the response kept by the writer in its format
+func (sw *streamWriter) render(name string) {
+	if sw.started || sw.code == 0 {
+		return
+	}
+	renderFormat(sw.ResponseWriter, sw.code, sw.body.Bytes(), sw.format, name)
 }
 
 var defaultAllowMethods = []string{
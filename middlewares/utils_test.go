@@ -2,10 +2,12 @@ package middlewares
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/prest/prest/config"
 	"github.com/prest/prest/middlewares/statements"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_getVars(t *testing.T) {
@@ -13,6 +15,17 @@ func Test_getVars(t *testing.T) {
 	if paths != nil {
 		t.Errorf("expected nil, got %s", paths)
 	}
+
+	paths = getVars("/prest/public/test/1")
+	require.Equal(t, map[string]string{"database": "prest", "schema": "public", "table": "test", "pk": "1"}, paths)
+	paths = getVars("/batch/prest/public/test")
+	require.Nil(t, paths)
+	paths = getVars("/_tx/0af3/commit")
+	require.Nil(t, paths)
+	paths = getVars("/_RPC/prest/public/add")
+	require.Nil(t, paths)
+	paths = getVars("/show/prest/public/test")
+	require.Nil(t, paths)
 }
 
 func Test_permissionByMethod(t *testing.T) {
@@ -111,3 +124,54 @@ func TestMatchURL(t *testing.T) {
 		})
 	}
 }
+
+func Test_streamWriter(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	sw := &streamWriter{ResponseWriter: recorder}
+	sw.Header().Set("Content-Type", "application/x-ndjson")
+	sw.WriteHeader(http.StatusOK)
+	sw.Write([]byte("{\"id\":1}\n"))
+	sw.render("test")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "{\"id\":1}\n", recorder.Body.String())
+	require.Equal(t, "application/x-ndjson", recorder.Header().Get("Content-Type"))
+
+	recorder = httptest.NewRecorder()
+	sw = &streamWriter{ResponseWriter: recorder}
+	http.Error(sw, "invalid stream format", http.StatusBadRequest)
+	require.Equal(t, 0, recorder.Body.Len())
+	sw.render("test")
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	require.JSONEq(t, `{"error": "invalid stream format"}`, recorder.Body.String())
+	require.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+}
+
+func Test_streamWriterTabular(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	sw := &streamWriter{ResponseWriter: recorder, format: "csv"}
+	sw.Header().Set("Content-Type", "text/csv")
+	sw.WriteHeader(http.StatusOK)
+	sw.Write([]byte("id\n1\n"))
+	sw.render("test")
+	require.Equal(t, "id\n1\n", recorder.Body.String())
+	require.Equal(t, "", recorder.Header().Get("Content-Disposition"))
+
+	recorder = httptest.NewRecorder()
+	sw = &streamWriter{ResponseWriter: recorder, format: "tsv"}
+	sw.Header().Set("Content-Type", "application/json")
+	sw.Write([]byte(`[{"name":"prest","id":1}]`))
+	require.Equal(t, 0, recorder.Body.Len())
+	sw.render("databases")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "id\tname\n1\tprest\n", recorder.Body.String())
+	require.Equal(t, "text/tab-separated-values", recorder.Header().Get("Content-Type"))
+	require.Equal(t, "attachment; filename=databases.tsv", recorder.Header().Get("Content-Disposition"))
+
+	recorder = httptest.NewRecorder()
+	sw = &streamWriter{ResponseWriter: recorder, format: "csv"}
+	http.Error(sw, "table not found", http.StatusNotFound)
+	sw.render("test")
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+	require.JSONEq(t, `{"error": "table not found"}`, recorder.Body.String())
+	require.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+}
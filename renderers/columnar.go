@@ -0,0 +1,379 @@
+package renderers
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v14/arrow"
+	"github.com/apache/arrow/go/v14/arrow/array"
+	"github.com/apache/arrow/go/v14/arrow/decimal128"
+	"github.com/apache/arrow/go/v14/arrow/ipc"
+	"github.com/apache/arrow/go/v14/arrow/memory"
+	"github.com/apache/arrow/go/v14/parquet"
+	"github.com/apache/arrow/go/v14/parquet/compress"
+	"github.com/apache/arrow/go/v14/parquet/pqarrow"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/prest/prest/adapters"
+)
+
+const (
+	// arrowBatchRows is the number of rows of the record batches of the
+	// arrow streams
+	arrowBatchRows = 4 << 10
+	// parquetRowGroupRows is the number of rows of the row groups of the
+	// parquet files
+	parquetRowGroupRows = 64 << 10
+)
+
+// ErrColumnar is returned when a value does not match the arrow type of
+// its column
+var ErrColumnar = errors.New("invalid columnar value")
+
+// IsColumnar returns true for the formats that are written from the
+// values and the types of the columns of the result, never from json
+func IsColumnar(format string) bool {
+	return format == Arrow || format == Parquet
+}
+
+// renderColumnar is the render function of the columnar formats, their
+// types are lost on json
+func renderColumnar(w io.Writer, body []byte) error {
+	return errors.Wrap(ErrNotAcceptable, "only table reads and scripts are available as arrow or parquet")
+}
+
+// ColumnarWriter writes rows as an arrow stream or a parquet file, typed
+// by the columns of the result
+type ColumnarWriter struct {
+	schema  *arrow.Schema
+	builder *array.RecordBuilder
+	appends []appendFunc
+	rows    int
+	maxRows int
+	write   func(rec arrow.Record) error
+	close   func() error
+}
+
+// appendFunc appends a value of the driver to an arrow builder
+type appendFunc func(b array.Builder, value interface{}) error
+
+// NewColumnarWriter returns a writer of the columnar format on w
+func NewColumnarWriter(w io.Writer, format string, columns []adapters.Column) (cw *ColumnarWriter, err error) {
+	fields := make([]arrow.Field, len(columns))
+	appends := make([]appendFunc, len(columns))
+	for i, column := range columns {
+		var dt arrow.DataType
+		dt, appends[i] = arrowType(column)
+		fields[i] = arrow.Field{Name: column.Name, Type: dt, Nullable: true}
+	}
+	schema := arrow.NewSchema(fields, nil)
+	cw = &ColumnarWriter{
+		schema:  schema,
+		builder: array.NewRecordBuilder(memory.DefaultAllocator, schema),
+		appends: appends,
+	}
+	switch format {
+	case Arrow:
+		iw := ipc.NewWriter(w, ipc.WithSchema(schema))
+		cw.maxRows, cw.write, cw.close = arrowBatchRows, iw.Write, iw.Close
+	case Parquet:
+		props := parquet.NewWriterProperties(
+			parquet.WithCompression(compress.Codecs.Snappy),
+			parquet.WithMaxRowGroupLength(parquetRowGroupRows),
+		)
+		var fw *pqarrow.FileWriter
+		fw, err = pqarrow.NewFileWriter(schema, w, props, pqarrow.NewArrowWriterProperties(pqarrow.WithStoreSchema()))
+		if err != nil {
+			cw.builder.Release()
+			return nil, err
+		}
+		cw.maxRows, cw.write, cw.close = parquetRowGroupRows, fw.Write, fw.Close
+	default:
+		cw.builder.Release()
+		return nil, fmt.Errorf("%s is not a columnar format", format)
+	}
+	return
+}
+
+// Write appends a row of values of the driver, a record batch is written
+// when it is full
+func (cw *ColumnarWriter) Write(values []interface{}) (err error) {
+	for i, value := range values {
+		if err = cw.appends[i](cw.builder.Field(i), value); err != nil {
+			return errors.Wrapf(err, "column %s", cw.schema.Field(i).Name)
+		}
+	}
+	cw.rows++
+	if cw.rows >= cw.maxRows {
+		return cw.flush()
+	}
+	return
+}
+
+// flush writes the rows appended as a record batch
+func (cw *ColumnarWriter) flush() (err error) {
+	if cw.rows == 0 {
+		return
+	}
+	rec := cw.builder.NewRecord()
+	defer rec.Release()
+	cw.rows = 0
+	return cw.write(rec)
+}
+
+// Close writes the last record batch and the end of the stream or the
+// footer of the file
+func (cw *ColumnarWriter) Close() (err error) {
+	defer cw.builder.Release()
+	if err = cw.flush(); err != nil {
+		return
+	}
+	return cw.close()
+}
+
+// arrowType maps the type of a column of the database to an arrow type and
+// the function that appends its values, the types without a mapping are
+// written as their text
+func arrowType(column adapters.Column) (arrow.DataType, appendFunc) {
+	if elem, isArray := strings.CutPrefix(column.Type, "_"); isArray {
+		return arrowListType(elem)
+	}
+	switch column.Type {
+	case "INT2":
+		return arrow.PrimitiveTypes.Int16, appendInt
+	case "INT4":
+		return arrow.PrimitiveTypes.Int32, appendInt
+	case "INT8":
+		return arrow.PrimitiveTypes.Int64, appendInt
+	case "FLOAT4":
+		return arrow.PrimitiveTypes.Float32, appendFloat
+	case "FLOAT8":
+		return arrow.PrimitiveTypes.Float64, appendFloat
+	case "BOOL":
+		return arrow.FixedWidthTypes.Boolean, appendBool
+	case "NUMERIC":
+		// numerics without a declared precision are kept exact as text
+		if column.Precision > 0 && column.Precision <= 38 {
+			dt := &arrow.Decimal128Type{Precision: int32(column.Precision), Scale: int32(column.Scale)}
+			return dt, appendDecimal(dt)
+		}
+	case "DATE":
+		return arrow.FixedWidthTypes.Date32, appendDate
+	case "TIMESTAMP":
+		return &arrow.TimestampType{Unit: arrow.Microsecond}, appendTimestamp
+	case "TIMESTAMPTZ":
+		return &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}, appendTimestamp
+	case "TIME":
+		return arrow.FixedWidthTypes.Time64us, appendTime
+	case "BYTEA":
+		return arrow.BinaryTypes.Binary, appendBinary
+	}
+	return arrow.BinaryTypes.String, appendString
+}
+
+// arrowListType maps the arrays of integers, floats, booleans and text
+// to arrow lists, the other arrays are written as their text
+func arrowListType(elem string) (arrow.DataType, appendFunc) {
+	switch elem {
+	case "INT2", "INT4", "INT8":
+		dt, _ := arrowType(adapters.Column{Type: elem})
+		return arrow.ListOf(dt), appendList(func() interface{} { return &[]sql.NullInt64{} })
+	case "FLOAT4", "FLOAT8":
+		dt, _ := arrowType(adapters.Column{Type: elem})
+		return arrow.ListOf(dt), appendList(func() interface{} { return &[]sql.NullFloat64{} })
+	case "BOOL":
+		return arrow.ListOf(arrow.FixedWidthTypes.Boolean), appendList(func() interface{} { return &[]sql.NullBool{} })
+	case "TEXT", "VARCHAR", "BPCHAR":
+		return arrow.ListOf(arrow.BinaryTypes.String), appendList(func() interface{} { return &[]sql.NullString{} })
+	}
+	return arrow.BinaryTypes.String, appendString
+}
+
+// invalidValue returns the error of a value of an unexpected type
+func invalidValue(value interface{}) error {
+	return errors.Wrapf(ErrColumnar, "%T", value)
+}
+
+func appendInt(b array.Builder, value interface{}) error {
+	v, ok := value.(int64)
+	switch {
+	case value == nil:
+		b.AppendNull()
+	case !ok:
+		return invalidValue(value)
+	case b.Type().ID() == arrow.INT16:
+		b.(*array.Int16Builder).Append(int16(v))
+	case b.Type().ID() == arrow.INT32:
+		b.(*array.Int32Builder).Append(int32(v))
+	default:
+		b.(*array.Int64Builder).Append(v)
+	}
+	return nil
+}
+
+func appendFloat(b array.Builder, value interface{}) error {
+	v, ok := value.(float64)
+	switch {
+	case value == nil:
+		b.AppendNull()
+	case !ok:
+		return invalidValue(value)
+	case b.Type().ID() == arrow.FLOAT32:
+		b.(*array.Float32Builder).Append(float32(v))
+	default:
+		b.(*array.Float64Builder).Append(v)
+	}
+	return nil
+}
+
+func appendBool(b array.Builder, value interface{}) error {
+	v, ok := value.(bool)
+	switch {
+	case value == nil:
+		b.AppendNull()
+	case !ok:
+		return invalidValue(value)
+	default:
+		b.(*array.BooleanBuilder).Append(v)
+	}
+	return nil
+}
+
+func appendDecimal(dt *arrow.Decimal128Type) appendFunc {
+	return func(b array.Builder, value interface{}) error {
+		if value == nil {
+			b.AppendNull()
+			return nil
+		}
+		n, err := decimal128.FromString(text(value), dt.Precision, dt.Scale)
+		if err != nil {
+			return errors.Wrapf(ErrColumnar, "%v", err)
+		}
+		b.(*array.Decimal128Builder).Append(n)
+		return nil
+	}
+}
+
+func appendDate(b array.Builder, value interface{}) error {
+	v, ok := value.(time.Time)
+	switch {
+	case value == nil:
+		b.AppendNull()
+	case !ok:
+		return invalidValue(value)
+	default:
+		b.(*array.Date32Builder).Append(arrow.Date32FromTime(v))
+	}
+	return nil
+}
+
+func appendTimestamp(b array.Builder, value interface{}) error {
+	v, ok := value.(time.Time)
+	switch {
+	case value == nil:
+		b.AppendNull()
+	case !ok:
+		return invalidValue(value)
+	default:
+		b.(*array.TimestampBuilder).Append(arrow.Timestamp(v.UnixMicro()))
+	}
+	return nil
+}
+
+func appendTime(b array.Builder, value interface{}) error {
+	v, ok := value.(time.Time)
+	switch {
+	case value == nil:
+		b.AppendNull()
+	case !ok:
+		return invalidValue(value)
+	default:
+		midnight := time.Date(v.Year(), v.Month(), v.Day(), 0, 0, 0, 0, v.Location())
+		b.(*array.Time64Builder).Append(arrow.Time64(v.Sub(midnight).Microseconds()))
+	}
+	return nil
+}
+
+func appendBinary(b array.Builder, value interface{}) error {
+	v, ok := value.([]byte)
+	switch {
+	case value == nil:
+		b.AppendNull()
+	case !ok:
+		return invalidValue(value)
+	default:
+		b.(*array.BinaryBuilder).Append(v)
+	}
+	return nil
+}
+
+func appendString(b array.Builder, value interface{}) error {
+	if value == nil {
+		b.AppendNull()
+		return nil
+	}
+	b.(*array.StringBuilder).Append(text(value))
+	return nil
+}
+
+// appendList returns the function that parses the text of a postgres
+// array into the slice returned by elems and appends it to a list
+func appendList(elems func() interface{}) appendFunc {
+	return func(b array.Builder, value interface{}) error {
+		if value == nil {
+			b.AppendNull()
+			return nil
+		}
+		dest := elems()
+		if err := (pq.GenericArray{A: dest}).Scan(value); err != nil {
+			return errors.Wrapf(ErrColumnar, "%v", err)
+		}
+		lb := b.(*array.ListBuilder)
+		lb.Append(true)
+		vb := lb.ValueBuilder()
+		switch elems := dest.(type) {
+		case *[]sql.NullInt64:
+			for _, e := range *elems {
+				appendInt(vb, nullable(e.Int64, e.Valid))
+			}
+		case *[]sql.NullFloat64:
+			for _, e := range *elems {
+				appendFloat(vb, nullable(e.Float64, e.Valid))
+			}
+		case *[]sql.NullBool:
+			for _, e := range *elems {
+				appendBool(vb, nullable(e.Bool, e.Valid))
+			}
+		case *[]sql.NullString:
+			for _, e := range *elems {
+				appendString(vb, nullable(e.String, e.Valid))
+			}
+		}
+		return nil
+	}
+}
+
+// nullable returns nil for an invalid sql.Null value
+func nullable(value interface{}, valid bool) interface{} {
+	if !valid {
+		return nil
+	}
+	return value
+}
+
+// text returns the text of a value of the driver
+func text(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,123 @@
+package renderers
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v14/arrow"
+	"github.com/apache/arrow/go/v14/arrow/array"
+	"github.com/apache/arrow/go/v14/arrow/decimal128"
+	"github.com/apache/arrow/go/v14/arrow/ipc"
+	"github.com/apache/arrow/go/v14/arrow/memory"
+	"github.com/apache/arrow/go/v14/parquet/file"
+	"github.com/apache/arrow/go/v14/parquet/pqarrow"
+	"github.com/prest/prest/adapters"
+	"github.com/stretchr/testify/require"
+)
+
+var columnarColumns = []adapters.Column{
+	{Name: "id", Type: "INT4"},
+	{Name: "price", Type: "NUMERIC", Precision: 10, Scale: 2},
+	{Name: "created_at", Type: "TIMESTAMPTZ"},
+	{Name: "tags", Type: "_TEXT"},
+	{Name: "name", Type: "VARCHAR"},
+}
+
+var columnarRows = [][]interface{}{
+	{int64(1), []byte("10.50"), time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC), []byte(`{a,"b c"}`), "prest"},
+	{int64(2), nil, nil, []byte(`{NULL}`), nil},
+}
+
+func TestArrowType(t *testing.T) {
+	var testCases = []struct {
+		description string
+		column      adapters.Column
+		expected    arrow.DataType
+	}{
+		{"Integer", adapters.Column{Type: "INT8"}, arrow.PrimitiveTypes.Int64},
+		{"Declared numeric", adapters.Column{Type: "NUMERIC", Precision: 12, Scale: 4}, &arrow.Decimal128Type{Precision: 12, Scale: 4}},
+		{"Numeric without precision", adapters.Column{Type: "NUMERIC", Precision: 65535, Scale: 65531}, arrow.BinaryTypes.String},
+		{"Date", adapters.Column{Type: "DATE"}, arrow.FixedWidthTypes.Date32},
+		{"Timestamp", adapters.Column{Type: "TIMESTAMP"}, &arrow.TimestampType{Unit: arrow.Microsecond}},
+		{"Array of floats", adapters.Column{Type: "_FLOAT8"}, arrow.ListOf(arrow.PrimitiveTypes.Float64)},
+		{"Array of json", adapters.Column{Type: "_JSONB"}, arrow.BinaryTypes.String},
+		{"Json", adapters.Column{Type: "JSONB"}, arrow.BinaryTypes.String},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			dt, _ := arrowType(tc.column)
+			require.True(t, arrow.TypeEqual(tc.expected, dt), "expected %s, got %s", tc.expected, dt)
+		})
+	}
+}
+
+func writeColumnar(t *testing.T, format string) []byte {
+	var buf bytes.Buffer
+	cw, err := NewColumnarWriter(&buf, format, columnarColumns)
+	require.NoError(t, err)
+	for _, row := range columnarRows {
+		require.NoError(t, cw.Write(row))
+	}
+	require.NoError(t, cw.Close())
+	return buf.Bytes()
+}
+
+func requireColumnarRecord(t *testing.T, rec arrow.Record) {
+	require.Equal(t, int64(2), rec.NumRows())
+	require.Equal(t, "id", rec.Schema().Field(0).Name)
+	require.Equal(t, int32(1), rec.Column(0).(*array.Int32).Value(0))
+	require.Equal(t, decimal128.FromI64(1050), rec.Column(1).(*array.Decimal128).Value(0))
+	require.True(t, rec.Column(1).IsNull(1))
+	require.Equal(t, arrow.Timestamp(1672628645000000), rec.Column(2).(*array.Timestamp).Value(0))
+	tags := rec.Column(3).(*array.List)
+	require.Equal(t, `["a","b c"]`, tags.ValueStr(0))
+	require.Equal(t, `[null]`, tags.ValueStr(1))
+	require.Equal(t, "prest", rec.Column(4).(*array.String).Value(0))
+	require.True(t, rec.Column(4).IsNull(1))
+}
+
+func TestColumnarWriterArrow(t *testing.T) {
+	r, err := ipc.NewReader(bytes.NewReader(writeColumnar(t, Arrow)))
+	require.NoError(t, err)
+	defer r.Release()
+	require.True(t, r.Next())
+	requireColumnarRecord(t, r.Record())
+	require.False(t, r.Next())
+}
+
+func TestColumnarWriterParquet(t *testing.T) {
+	pf, err := file.NewParquetReader(bytes.NewReader(writeColumnar(t, Parquet)))
+	require.NoError(t, err)
+	fr, err := pqarrow.NewFileReader(pf, pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+	require.NoError(t, err)
+	tbl, err := fr.ReadTable(context.Background())
+	require.NoError(t, err)
+	defer tbl.Release()
+	tr := array.NewTableReader(tbl, -1)
+	defer tr.Release()
+	require.True(t, tr.Next())
+	requireColumnarRecord(t, tr.Record())
+}
+
+func TestColumnarWriterEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	cw, err := NewColumnarWriter(&buf, Arrow, columnarColumns)
+	require.NoError(t, err)
+	require.NoError(t, cw.Close())
+	r, err := ipc.NewReader(&buf)
+	require.NoError(t, err)
+	defer r.Release()
+	require.Equal(t, 5, len(r.Schema().Fields()))
+	require.False(t, r.Next())
+}
+
+func TestColumnarWriterInvalidValue(t *testing.T) {
+	var buf bytes.Buffer
+	cw, err := NewColumnarWriter(&buf, Arrow, columnarColumns)
+	require.NoError(t, err)
+	err = cw.Write([]interface{}{"1", nil, nil, nil, nil})
+	require.ErrorIs(t, err, ErrColumnar)
+}
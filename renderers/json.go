@@ -0,0 +1,51 @@
+package renderers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// renderJSON writes the body as is
+func renderJSON(w io.Writer, body []byte) (err error) {
+	_, err = w.Write(body)
+	return
+}
+
+// renderNDJSON writes each object of a json array on its own line, a
+// single object is written as one line
+func renderNDJSON(w io.Writer, body []byte) (err error) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || body[0] != '[' {
+		return writeLine(w, body)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	if _, err = decoder.Token(); err != nil {
+		return
+	}
+	for decoder.More() {
+		var row json.RawMessage
+		if err = decoder.Decode(&row); err != nil {
+			return
+		}
+		if err = writeLine(w, row); err != nil {
+			return
+		}
+	}
+	_, err = decoder.Token()
+	return
+}
+
+// writeLine writes a json value compacted on a single line
+func writeLine(w io.Writer, value []byte) (err error) {
+	if len(value) == 0 {
+		return
+	}
+	var buf bytes.Buffer
+	if err = json.Compact(&buf, value); err != nil {
+		return
+	}
+	buf.WriteByte('\n')
+	_, err = w.Write(buf.Bytes())
+	return
+}
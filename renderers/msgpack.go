@@ -0,0 +1,138 @@
+package renderers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// renderMsgPack writes the body as MessagePack, the keys of the objects
+// are sorted by name since json objects are not ordered
+func renderMsgPack(w io.Writer, body []byte) (err error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err = decoder.Decode(&value); err != nil {
+		return
+	}
+	bw := bufio.NewWriter(w)
+	if err = encodeMsgPack(bw, value); err != nil {
+		return
+	}
+	return bw.Flush()
+}
+
+// encodeMsgPack writes a decoded json value as MessagePack
+func encodeMsgPack(w *bufio.Writer, value interface{}) (err error) {
+	switch v := value.(type) {
+	case nil:
+		return w.WriteByte(0xc0)
+	case bool:
+		if v {
+			return w.WriteByte(0xc3)
+		}
+		return w.WriteByte(0xc2)
+	case json.Number:
+		return encodeMsgPackNumber(w, v)
+	case string:
+		writeMsgPackHeader(w, len(v), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		_, err = w.WriteString(v)
+		return
+	case []interface{}:
+		writeMsgPackHeader(w, len(v), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, item := range v {
+			if err = encodeMsgPack(w, item); err != nil {
+				return
+			}
+		}
+		return
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		writeMsgPackHeader(w, len(v), 0x80, 16, 0, 0xde, 0xdf)
+		for _, key := range keys {
+			if err = encodeMsgPack(w, key); err != nil {
+				return
+			}
+			if err = encodeMsgPack(w, v[key]); err != nil {
+				return
+			}
+		}
+		return
+	}
+	return fmt.Errorf("msgpack: unsupported value %v", value)
+}
+
+// writeMsgPackHeader writes the type and the length of a string, an array
+// or a map: the fixed type when the length is below fixLimit, otherwise
+// the 8 (when there is one), 16 or 32 bits length types
+func writeMsgPackHeader(w *bufio.Writer, n int, fix byte, fixLimit int, t8, t16, t32 byte) {
+	var buf [5]byte
+	switch {
+	case n < fixLimit:
+		w.WriteByte(fix | byte(n))
+	case t8 != 0 && n <= math.MaxUint8:
+		w.Write([]byte{t8, byte(n)})
+	case n <= math.MaxUint16:
+		buf[0] = t16
+		binary.BigEndian.PutUint16(buf[1:], uint16(n))
+		w.Write(buf[:3])
+	default:
+		buf[0] = t32
+		binary.BigEndian.PutUint32(buf[1:], uint32(n))
+		w.Write(buf[:5])
+	}
+}
+
+// encodeMsgPackNumber writes a json number in the smallest MessagePack
+// signed integer type that holds it, unsigned 64 bits integers above it
+// and floats as 64 bits floats
+func encodeMsgPackNumber(w *bufio.Writer, n json.Number) (err error) {
+	var buf [9]byte
+	if i, parseErr := strconv.ParseInt(n.String(), 10, 64); parseErr == nil {
+		switch {
+		case i >= 0 && i <= math.MaxInt8:
+			return w.WriteByte(byte(i))
+		case i < 0 && i >= -32:
+			return w.WriteByte(byte(int8(i)))
+		case i >= math.MinInt8 && i <= math.MaxInt8:
+			_, err = w.Write([]byte{0xd0, byte(int8(i))})
+		case i >= math.MinInt16 && i <= math.MaxInt16:
+			buf[0] = 0xd1
+			binary.BigEndian.PutUint16(buf[1:], uint16(int16(i)))
+			_, err = w.Write(buf[:3])
+		case i >= math.MinInt32 && i <= math.MaxInt32:
+			buf[0] = 0xd2
+			binary.BigEndian.PutUint32(buf[1:], uint32(int32(i)))
+			_, err = w.Write(buf[:5])
+		default:
+			buf[0] = 0xd3
+			binary.BigEndian.PutUint64(buf[1:], uint64(i))
+			_, err = w.Write(buf[:9])
+		}
+		return
+	}
+	if u, parseErr := strconv.ParseUint(n.String(), 10, 64); parseErr == nil {
+		buf[0] = 0xcf
+		binary.BigEndian.PutUint64(buf[1:], u)
+		_, err = w.Write(buf[:9])
+		return
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return
+	}
+	buf[0] = 0xcb
+	binary.BigEndian.PutUint64(buf[1:], math.Float64bits(f))
+	_, err = w.Write(buf[:9])
+	return
+}
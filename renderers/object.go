@@ -0,0 +1,45 @@
+package renderers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNotFound is returned when a single object is requested from no rows
+var ErrNotFound = errors.New("not found")
+
+// SingleObject returns the object of a json array of a single row, an
+// object is returned as is. ErrNotFound is returned when there are no
+// rows and ErrNotAcceptable when there are several
+func SingleObject(body []byte) (object []byte, err error) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || body[0] != '[' {
+		return body, nil
+	}
+	var rows []json.RawMessage
+	if err = json.Unmarshal(body, &rows); err != nil {
+		return
+	}
+	switch len(rows) {
+	case 0:
+		err = errors.Wrap(ErrNotFound, "no rows for a single object")
+	case 1:
+		object = rows[0]
+	default:
+		err = errors.Wrap(ErrNotAcceptable, "several rows for a single object")
+	}
+	return
+}
+
+// renderObject writes the object of a json array of a single row
+func renderObject(w io.Writer, body []byte) (err error) {
+	object, err := SingleObject(body)
+	if err != nil {
+		return
+	}
+	_, err = w.Write(object)
+	return
+}
@@ -0,0 +1,266 @@
+// Package renderers holds the output formats of the responses of pREST
+//
+// The formats are chosen by the `_renderer` query parameter or negotiated
+// from the Accept header, embedders and plugins add formats with Register
+package renderers
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// JSON renders the response as is
+	JSON = "json"
+	// XML renders the response as xml
+	XML = "xml"
+	// CSV renders the rows of the response as comma separated values
+	CSV = "csv"
+	// TSV renders the rows of the response as tab separated values
+	TSV = "tsv"
+	// NDJSON renders the rows of the response as one json object per line
+	NDJSON = "ndjson"
+	// MsgPack renders the response as MessagePack
+	MsgPack = "msgpack"
+	// Arrow renders the rows of the response as an Apache Arrow IPC stream
+	Arrow = "arrow"
+	// Parquet renders the rows of the response as an Apache Parquet file
+	Parquet = "parquet"
+	// Object renders the single row of the response as a json object
+	Object = "object"
+)
+
+// ErrNotAcceptable is returned when no format of the request is registered
+var ErrNotAcceptable = errors.New("not acceptable")
+
+// Renderer writes a json response body in another format
+type Renderer interface {
+	// ContentType returns the media type of the rendered responses
+	ContentType() string
+	// Render writes the json body in the format of the renderer
+	Render(w io.Writer, body []byte) error
+}
+
+type renderer struct {
+	contentType string
+	render      func(w io.Writer, body []byte) error
+}
+
+func (r renderer) ContentType() string {
+	return r.contentType
+}
+
+func (r renderer) Render(w io.Writer, body []byte) error {
+	return r.render(w, body)
+}
+
+// New returns a Renderer of contentType that renders with fn
+func New(contentType string, fn func(w io.Writer, body []byte) error) Renderer {
+	return renderer{contentType: contentType, render: fn}
+}
+
+var (
+	mtx        sync.RWMutex
+	registry   = make(map[string]Renderer)
+	mediaTypes = make(map[string]string)
+)
+
+// mediaTypeAliases maps other media types of the Accept header to the
+// media type of a built-in format
+var mediaTypeAliases = map[string]string{
+	"text/xml":              "application/xml",
+	"application/x-msgpack": "application/msgpack",
+}
+
+func init() {
+	Register(JSON, New("application/json", renderJSON))
+	Register(XML, New("application/xml", renderXML))
+	Register(CSV, New("text/csv", tabularRenderer(CSV)))
+	Register(TSV, New("text/tab-separated-values", tabularRenderer(TSV)))
+	Register(NDJSON, New("application/x-ndjson", renderNDJSON))
+	Register(MsgPack, New("application/msgpack", renderMsgPack))
+	Register(Arrow, New("application/vnd.apache.arrow.stream", renderColumnar))
+	Register(Parquet, New("application/vnd.apache.parquet", renderColumnar))
+	Register(Object, New("application/vnd.prest.object+json", renderObject))
+}
+
+// Register adds the renderer of a format, selected by `_renderer=format`
+// or by its content type on the Accept header. A renderer registered with
+// the name of an other format replaces it
+func Register(format string, r Renderer) {
+	mtx.Lock()
+	defer mtx.Unlock()
+	if previous, ok := registry[format]; ok {
+		delete(mediaTypes, mediaType(previous.ContentType()))
+	}
+	registry[format] = r
+	mediaTypes[mediaType(r.ContentType())] = format
+}
+
+// Get returns the renderer of a format
+func Get(format string) (r Renderer, ok bool) {
+	mtx.RLock()
+	defer mtx.RUnlock()
+	r, ok = registry[format]
+	return
+}
+
+// mediaType returns a media type without its parameters
+func mediaType(contentType string) string {
+	mt, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(mt))
+}
+
+// Format returns the format of the response of a request, `_renderer`
+// takes precedence over the Accept header and json is the default.
+// ErrNotAcceptable is returned when the format is not registered
+func Format(r *http.Request) (format string, err error) {
+	if format = r.URL.Query().Get("_renderer"); format != "" {
+		if _, ok := Get(format); !ok {
+			return "", errors.Wrapf(ErrNotAcceptable, "unsupported renderer %s", format)
+		}
+		return
+	}
+	accept := r.Header.Get("Accept")
+	format, ok := negotiate(accept)
+	if !ok {
+		err = errors.Wrapf(ErrNotAcceptable, "unsupported media type %s", accept)
+	}
+	return
+}
+
+// mediaRange is a media type of the Accept header with its quality
+type mediaRange struct {
+	mediaType string
+	q         float64
+}
+
+// negotiate returns the registered format with the highest quality on
+// the Accept header. json is returned when the header is empty, among the
+// formats of the same quality, and when the header accepts any type but
+// none of the preferred ones is registered
+func negotiate(accept string) (format string, ok bool) {
+	if strings.TrimSpace(accept) == "" {
+		return JSON, true
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, _ := strings.Cut(part, ";")
+		mr := mediaRange{mediaType: mediaType(mt), q: 1}
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(param, "=")
+			if strings.TrimSpace(key) != "q" {
+				continue
+			}
+			if q, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				mr.q = q
+			}
+		}
+		if mr.mediaType == "" || mr.q <= 0 {
+			continue
+		}
+		ranges = append(ranges, mr)
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+	anyType := false
+	for _, mr := range ranges {
+		anyType = anyType || mr.mediaType == "*/*"
+	}
+	var matched []string
+	for i, mr := range ranges {
+		if f, isFormat := formatOf(mr.mediaType); isFormat {
+			matched = append(matched, f)
+		}
+		if i+1 < len(ranges) && ranges[i+1].q == mr.q {
+			continue
+		}
+		if len(matched) > 0 {
+			break
+		}
+		// none of the preferred types is rendered, like the html of the
+		// browsers, json is the one of any type
+		if anyType {
+			return formatOf("*/*")
+		}
+	}
+	if len(matched) == 0 {
+		return "", false
+	}
+	// json is preferred among the formats of the same quality
+	for _, f := range matched {
+		if f == JSON {
+			return JSON, true
+		}
+	}
+	return matched[0], true
+}
+
+// formatOf returns the format of a media range, a wildcard selects json
+// when it matches, otherwise the first registered format by name
+func formatOf(mt string) (format string, ok bool) {
+	mtx.RLock()
+	defer mtx.RUnlock()
+	if alias, isAlias := mediaTypeAliases[mt]; isAlias {
+		mt = alias
+	}
+	if format, ok = mediaTypes[mt]; ok {
+		return
+	}
+	prefix, isWildcard := strings.CutSuffix(mt, "/*")
+	if !isWildcard {
+		return "", false
+	}
+	prefix += "/"
+	if prefix == "*/" {
+		prefix = ""
+	}
+	if r, ok := registry[JSON]; ok && strings.HasPrefix(mediaType(r.ContentType()), prefix) {
+		return JSON, true
+	}
+	var formats []string
+	for registered, f := range mediaTypes {
+		if strings.HasPrefix(registered, prefix) {
+			formats = append(formats, f)
+		}
+	}
+	if len(formats) == 0 {
+		return "", false
+	}
+	sort.Strings(formats)
+	return formats[0], true
+}
+
+// ContentType returns the content type of a format
+func ContentType(format string) string {
+	r, ok := Get(format)
+	if !ok {
+		return ""
+	}
+	return r.ContentType()
+}
+
+// IsTabular returns true for the formats that render one line per row
+func IsTabular(format string) bool {
+	return format == CSV || format == TSV
+}
+
+// Streamed returns true for the formats that table reads write as the
+// rows are read from the database
+func Streamed(format string) bool {
+	return IsTabular(format) || IsColumnar(format) || format == NDJSON
+}
+
+// Attachment returns the Content-Disposition of a download named after
+// name with the extension of the format
+func Attachment(name, format string) string {
+	return mime.FormatMediaType("attachment", map[string]string{"filename": name + "." + format})
+}
@@ -0,0 +1,174 @@
+package renderers
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	var testCases = []struct {
+		description string
+		url         string
+		accept      string
+		expected    string
+		err         error
+	}{
+		{"Default", "/prest-test/public/test", "", JSON, nil},
+		{"Renderer", "/prest-test/public/test?_renderer=xml", "", XML, nil},
+		{"Unknown renderer", "/prest-test/public/test?_renderer=yaml", "", "", ErrNotAcceptable},
+		{"Accept csv", "/prest-test/public/test", "text/csv", CSV, nil},
+		{"Accept tsv with parameters", "/prest-test/public/test", "text/html, text/tab-separated-values;charset=utf-8", TSV, nil},
+		{"Accept ndjson", "/prest-test/public/test", "application/x-ndjson", NDJSON, nil},
+		{"Accept msgpack alias", "/prest-test/public/test", "application/x-msgpack", MsgPack, nil},
+		{"Quality", "/prest-test/public/test", "application/json;q=0.5, application/msgpack", MsgPack, nil},
+		{"Excluded type", "/prest-test/public/test", "application/xml;q=0, text/html", "", ErrNotAcceptable},
+		{"Any type", "/prest-test/public/test", "text/html, */*;q=0.1", JSON, nil},
+		{"Any text type", "/prest-test/public/test", "text/*", CSV, nil},
+		{"Browser", "/prest-test/public/test", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", JSON, nil},
+		{"Same quality as json", "/prest-test/public/test", "application/xml, */*", JSON, nil},
+		{"Higher quality than any type", "/prest-test/public/test", "application/xml, */*;q=0.8", XML, nil},
+		{"Lower quality without any type", "/prest-test/public/test", "text/html, application/xml;q=0.9", XML, nil},
+		{"Renderer over accept", "/prest-test/public/test?_renderer=csv", "application/json", CSV, nil},
+		{"Unknown accept", "/prest-test/public/test", "text/html", "", ErrNotAcceptable},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodGet, tc.url, nil)
+			require.NoError(t, err)
+			r.Header.Set("Accept", tc.accept)
+			format, err := Format(r)
+			require.ErrorIs(t, err, tc.err)
+			require.Equal(t, tc.expected, format)
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register("yaml", New("application/yaml", func(w io.Writer, body []byte) error {
+		_, err := w.Write([]byte("yaml"))
+		return err
+	}))
+	defer func() {
+		mtx.Lock()
+		delete(registry, "yaml")
+		delete(mediaTypes, "application/yaml")
+		mtx.Unlock()
+	}()
+
+	r, err := http.NewRequest(http.MethodGet, "/prest-test/public/test", nil)
+	require.NoError(t, err)
+	r.Header.Set("Accept", "application/yaml")
+	format, err := Format(r)
+	require.NoError(t, err)
+	require.Equal(t, "yaml", format)
+	require.Equal(t, "application/yaml", ContentType(format))
+}
+
+func TestAttachment(t *testing.T) {
+	require.Equal(t, `attachment; filename=test.csv`, Attachment("test", CSV))
+	require.Equal(t, `attachment; filename="my report.tsv"`, Attachment("my report", TSV))
+}
+
+func TestJSONToTabular(t *testing.T) {
+	var testCases = []struct {
+		description string
+		format      string
+		body        string
+		expected    string
+	}{
+		{"Empty array", CSV, `[]`, ""},
+		{"Sorted columns", CSV, `[{"name":"prest","id":1}]`, "id,name\n1,prest\n"},
+		{"Single object", CSV, `{"id":1}`, "id\n1\n"},
+		{"Quoting", CSV, `[{"name":"a, \"b\"\nc"}]`, "name\n\"a, \"\"b\"\"\nc\"\n"},
+		{"Nulls and nested values", CSV, `[{"id":1,"tags":["a","b"],"parent":null}]`, "id,parent,tags\n1,,\"[\"\"a\"\",\"\"b\"\"]\"\n"},
+		{"Missing keys", CSV, `[{"id":1},{"name":"b"}]`, "id,name\n1,\n,b\n"},
+		{"Tab separated", TSV, `[{"id":12345678901234567890,"ok":true}]`, "id\tok\n12345678901234567890\ttrue\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, JSONToTabular(&buf, tc.format, []byte(tc.body)))
+			require.Equal(t, tc.expected, buf.String())
+		})
+	}
+
+	var buf bytes.Buffer
+	require.Error(t, JSONToTabular(&buf, CSV, []byte(`[1, 2]`)))
+}
+
+func TestRenderNDJSON(t *testing.T) {
+	var testCases = []struct {
+		description string
+		body        string
+		expected    string
+	}{
+		{"Array", "[{\"id\": 1}, {\"id\": 2}]", "{\"id\":1}\n{\"id\":2}\n"},
+		{"Empty array", "[]", ""},
+		{"Object", "{\n\t\"error\": \"not found\"\n}", "{\"error\":\"not found\"}\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, renderNDJSON(&buf, []byte(tc.body)))
+			require.Equal(t, tc.expected, buf.String())
+		})
+	}
+}
+
+func TestRenderMsgPack(t *testing.T) {
+	var testCases = []struct {
+		description string
+		body        string
+		expected    []byte
+	}{
+		{"Null", "null", []byte{0xc0}},
+		{"Booleans", "[true, false]", []byte{0x92, 0xc3, 0xc2}},
+		{"Small integers", "[1, -1, 127]", []byte{0x93, 0x01, 0xff, 0x7f}},
+		{"Integers", "[-100, 1000, 70000]", []byte{0x93, 0xd0, 0x9c, 0xd1, 0x03, 0xe8, 0xd2, 0x00, 0x01, 0x11, 0x70}},
+		{"Unsigned integer", "18446744073709551615", []byte{0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"Float", "1.5", []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+		{"Sorted map", `{"b": "x", "a": 1}`, []byte{0x82, 0xa1, 'a', 0x01, 0xa1, 'b', 0xa1, 'x'}},
+		{"String 8", `"` + strings.Repeat("a", 40) + `"`, append([]byte{0xd9, 40}, strings.Repeat("a", 40)...)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, renderMsgPack(&buf, []byte(tc.body)))
+			require.Equal(t, tc.expected, buf.Bytes())
+		})
+	}
+
+	var buf bytes.Buffer
+	require.Error(t, renderMsgPack(&buf, []byte("{")))
+}
+
+func TestSingleObject(t *testing.T) {
+	var testCases = []struct {
+		description string
+		body        string
+		expected    string
+		err         error
+	}{
+		{"Single row", `[{"id": 1}]`, `{"id": 1}`, nil},
+		{"Object", `{"count": 2}`, `{"count": 2}`, nil},
+		{"No rows", `[]`, "", ErrNotFound},
+		{"Several rows", `[{"id": 1}, {"id": 2}]`, "", ErrNotAcceptable},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			object, err := SingleObject([]byte(tc.body))
+			require.ErrorIs(t, err, tc.err)
+			require.Equal(t, tc.expected, string(object))
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package renderers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+)
+
+// NewTabularWriter returns a writer of rows as csv or tsv on w, fields
+// are quoted when they hold the separator, quotes or line breaks
+func NewTabularWriter(w io.Writer, format string) *csv.Writer {
+	cw := csv.NewWriter(w)
+	if format == TSV {
+		cw.Comma = '\t'
+	}
+	return cw
+}
+
+// JSONRows returns the columns and the rows of a json array of objects,
+// the columns are sorted by name since json objects are not ordered.
+// Nulls are empty fields and nested values are written as json
+func JSONRows(body []byte) (columns []string, rows [][]string, err error) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return
+	}
+	if body[0] == '{' {
+		body = append(append([]byte{'['}, body...), ']')
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var objects []map[string]interface{}
+	if err = decoder.Decode(&objects); err != nil {
+		return
+	}
+	seen := make(map[string]bool)
+	for _, object := range objects {
+		for key := range object {
+			if !seen[key] {
+				seen[key] = true
+				columns = append(columns, key)
+			}
+		}
+	}
+	sort.Strings(columns)
+	rows = make([][]string, 0, len(objects))
+	for _, object := range objects {
+		row := make([]string, len(columns))
+		for i, column := range columns {
+			if row[i], err = field(object[column]); err != nil {
+				return nil, nil, err
+			}
+		}
+		rows = append(rows, row)
+	}
+	return
+}
+
+// field returns the text of a json value
+func field(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		if v {
+			return "true", nil
+		}
+		return "false", nil
+	default:
+		byt, err := json.Marshal(v)
+		return string(byt), err
+	}
+}
+
+// tabularRenderer returns the render function of a tabular format
+func tabularRenderer(format string) func(w io.Writer, body []byte) error {
+	return func(w io.Writer, body []byte) error {
+		return JSONToTabular(w, format, body)
+	}
+}
+
+// JSONToTabular writes a json array of objects, or a single object, as
+// csv or tsv with a header row, an empty array is an empty body
+func JSONToTabular(w io.Writer, format string, body []byte) (err error) {
+	columns, rows, err := JSONRows(body)
+	if err != nil || len(columns) == 0 {
+		return
+	}
+	tw := NewTabularWriter(w, format)
+	if err = tw.Write(columns); err != nil {
+		return
+	}
+	return tw.WriteAll(rows)
+}
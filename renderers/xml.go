@@ -0,0 +1,18 @@
+package renderers
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/clbanning/mxj/j2x"
+)
+
+// renderXML writes the body as xml wrapped by an objects element
+func renderXML(w io.Writer, body []byte) (err error) {
+	xmldata, err := j2x.JsonToXml(body)
+	if err != nil {
+		return
+	}
+	_, err = fmt.Fprintf(w, "<objects>%s</objects>", xmldata)
+	return
+}
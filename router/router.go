@@ -39,15 +39,28 @@ func GetRouter() *mux.Router {
 	router.HandleFunc("/show/{database}/{schema}/{table}", controllers.ShowTable).Methods("GET")
 	crudRoutes := mux.NewRouter().PathPrefix("/").Subrouter().StrictSlash(true)
 	router.HandleFunc("/_health", controllers.WrappedHealthCheck(controllers.DefaultCheckList)).Methods("GET")
+	// transactions of the clients, see the Prest-Transaction header
+	crudRoutes.HandleFunc("/_tx", controllers.OpenTransaction).Methods("POST")
+	crudRoutes.HandleFunc("/_tx/{database}", controllers.OpenTransaction).Methods("POST")
+	crudRoutes.HandleFunc("/_tx/{id}/commit", controllers.CommitTransaction).Methods("POST")
+	crudRoutes.HandleFunc("/_tx/{id}/rollback", controllers.RollbackTransaction).Methods("POST")
+	// stored functions, before the rows of the tables
+	crudRoutes.HandleFunc("/_RPC/{database}/{schema}/{function}", controllers.CallFunction).Methods("GET", "POST")
 	crudRoutes.HandleFunc("/{database}/{schema}/{table}", controllers.SelectFromTables).Methods("GET")
 	crudRoutes.HandleFunc("/{database}/{schema}/{table}", controllers.InsertInTables).Methods("POST")
 	crudRoutes.HandleFunc("/batch/{database}/{schema}/{table}", controllers.BatchInsertInTables).Methods("POST")
+	crudRoutes.HandleFunc("/_batch", controllers.Batch).Methods("POST")
 	crudRoutes.HandleFunc("/{database}/{schema}/{table}", controllers.DeleteFromTable).Methods("DELETE")
 	crudRoutes.HandleFunc("/{database}/{schema}/{table}", controllers.UpdateTable).Methods("PUT", "PATCH")
+	// rows addressed by primary key
+	crudRoutes.HandleFunc("/{database}/{schema}/{table}/{pk}", controllers.SelectFromTables).Methods("GET")
+	crudRoutes.HandleFunc("/{database}/{schema}/{table}/{pk}", controllers.DeleteFromTable).Methods("DELETE")
+	crudRoutes.HandleFunc("/{database}/{schema}/{table}/{pk}", controllers.UpdateTable).Methods("PUT", "PATCH")
 	router.PathPrefix("/").Handler(negroni.New(
 		middlewares.ExposureMiddleware(),
 		middlewares.AccessControl(),
 		middlewares.AuthMiddleware(),
+		middlewares.TransactionMiddleware(),
 		middlewares.CacheMiddleware(),
 		// plugins middleware
 		plugins.MiddlewarePlugin(),
@@ -39,9 +39,17 @@ func TestDefaultRouters(t *testing.T) {
 		{"/{database}/{schema}/{table}", "GET", http.StatusUnauthorized},
 		{"/{database}/{schema}/{table}", "POST", http.StatusUnauthorized},
 		{"/batch/{database}/{schema}/{table}", "POST", http.StatusBadRequest},
+		{"/_batch", "POST", http.StatusBadRequest},
+		{"/_tx/{id}/commit", "POST", http.StatusNotFound},
+		{"/_tx/{id}/rollback", "POST", http.StatusNotFound},
+		{"/_RPC/{database}/{schema}/{function}", "GET", http.StatusBadRequest},
 		{"/{database}/{schema}/{table}", "DELETE", http.StatusUnauthorized},
 		{"/{database}/{schema}/{table}", "PUT", http.StatusUnauthorized},
 		{"/{database}/{schema}/{table}", "PATCH", http.StatusUnauthorized},
+		{"/{database}/{schema}/{table}/{pk}", "GET", http.StatusUnauthorized},
+		{"/{database}/{schema}/{table}/{pk}", "DELETE", http.StatusUnauthorized},
+		{"/{database}/{schema}/{table}/{pk}", "PUT", http.StatusUnauthorized},
+		{"/{database}/{schema}/{table}/{pk}", "PATCH", http.StatusUnauthorized},
 		{"/auth", "GET", http.StatusNotFound},
 		{"/", "GET", http.StatusNotFound},
 	}
@@ -45,3 +45,8 @@ INSERT INTO prest_users(username, password) VALUES('test@postgres.rest', 'e10adc
 CREATE TABLE table_to_view(id serial, name text, celphone text);
 INSERT INTO table_to_view (name, celphone) VALUES ('gopher', '8888888');
 CREATE VIEW view_test AS SELECT name AS player from table_to_view;
+
+-- Functions
+CREATE FUNCTION add_numbers(a integer, b integer DEFAULT 1) RETURNS integer AS $$ SELECT a + b $$ LANGUAGE sql IMMUTABLE;
+CREATE FUNCTION test4_by_name(search text) RETURNS SETOF test4 AS $$ SELECT * FROM test4 WHERE name LIKE search $$ LANGUAGE sql STABLE;
+CREATE FUNCTION insert_test4(new_name text) RETURNS test4 AS $$ INSERT INTO test4 (name) VALUES (new_name) RETURNING * $$ LANGUAGE sql;
@@ -1,12 +1,158 @@
+// Package transactions keeps the transactions opened by the clients, the
+// requests carrying the id of one on the Prest-Transaction header run in
+// it until it is committed, rolled back or reaped when idle
 package transactions
 
-import "net/http"
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
 
-// OpenTransactions contains a list of opentransactions
-var OpenTransactions map[string][]Content
+	"github.com/structy/log"
+)
 
-// Content contains a open transaction
-type Content struct {
-	Request *http.Request    `json:"request"`
-	Next    http.HandlerFunc `json:"next_func"`
+// Header is the request header of the id of the transaction the request
+// runs in
+const Header = "Prest-Transaction"
+
+var (
+	// ErrNotFound is returned for the ids of transactions that are not open
+	ErrNotFound = errors.New("transaction not found")
+	// ErrTooMany is returned when the maximum of transactions are open
+	ErrTooMany = errors.New("too many open transactions")
+)
+
+// Transaction is a transaction opened by a client, its requests run one
+// at a time
+type Transaction struct {
+	ID       string
+	Database string
+	Tx       *sql.Tx
+	mtx      sync.Mutex
+	lastUsed time.Time
+	closed   bool
+}
+
+var (
+	mtx sync.Mutex
+	// OpenTransactions contains the open transactions by id
+	OpenTransactions = make(map[string]*Transaction)
+	reaper           sync.Once
+)
+
+// Open keeps tx, begun on database, and returns its id. ErrTooMany is
+// returned when max transactions are already open, 0 is no maximum
+func Open(tx *sql.Tx, database string, max int) (id string, err error) {
+	b := make([]byte, 16)
+	if _, err = rand.Read(b); err != nil {
+		return
+	}
+	id = hex.EncodeToString(b)
+	mtx.Lock()
+	defer mtx.Unlock()
+	if max > 0 && len(OpenTransactions) >= max {
+		return "", ErrTooMany
+	}
+	OpenTransactions[id] = &Transaction{
+		ID:       id,
+		Database: database,
+		Tx:       tx,
+		lastUsed: time.Now(),
+	}
+	return
+}
+
+// Count returns the number of open transactions
+func Count() int {
+	mtx.Lock()
+	defer mtx.Unlock()
+	return len(OpenTransactions)
+}
+
+// Acquire returns the open transaction of id once the request running in
+// it is done, Release lets the next request run
+func Acquire(id string) (t *Transaction, err error) {
+	mtx.Lock()
+	t, ok := OpenTransactions[id]
+	mtx.Unlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	t.mtx.Lock()
+	// committed, rolled back or reaped while waiting
+	if t.closed {
+		t.mtx.Unlock()
+		return nil, ErrNotFound
+	}
+	return t, nil
+}
+
+// Release marks an acquired transaction as used and lets the next request
+// run in it
+func (t *Transaction) Release() {
+	t.lastUsed = time.Now()
+	t.mtx.Unlock()
+}
+
+// Commit commits an acquired transaction, it is no longer open
+func (t *Transaction) Commit() error {
+	t.close()
+	return t.Tx.Commit()
+}
+
+// Rollback rolls back an acquired transaction, it is no longer open
+func (t *Transaction) Rollback() error {
+	t.close()
+	return t.Tx.Rollback()
+}
+
+func (t *Transaction) close() {
+	t.closed = true
+	mtx.Lock()
+	delete(OpenTransactions, t.ID)
+	mtx.Unlock()
+}
+
+// Reap rolls back the transactions idle for longer than timeout, those
+// running a request are not idle
+func Reap(timeout time.Duration) (reaped int) {
+	mtx.Lock()
+	open := make([]*Transaction, 0, len(OpenTransactions))
+	for _, t := range OpenTransactions {
+		open = append(open, t)
+	}
+	mtx.Unlock()
+	for _, t := range open {
+		if !t.mtx.TryLock() {
+			continue
+		}
+		if !t.closed && time.Since(t.lastUsed) > timeout {
+			if err := t.Rollback(); err != nil {
+				log.Errorln(err)
+			}
+			log.Warningf("transaction %s rolled back after %v idle\n", t.ID, timeout)
+			reaped++
+		}
+		t.mtx.Unlock()
+	}
+	return
+}
+
+// StartReaper reaps the transactions idle for longer than timeout in the
+// background, it is started once
+func StartReaper(timeout time.Duration) {
+	reaper.Do(func() {
+		interval := timeout / 2
+		if interval < time.Second {
+			interval = time.Second
+		}
+		go func() {
+			for range time.Tick(interval) {
+				Reap(timeout)
+			}
+		}()
+	})
 }
@@ -0,0 +1,84 @@
+package transactions
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/prest/prest/adapters/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func beginTx(t *testing.T) *sql.Tx {
+	tx, err := mock.New(t).GetTransactionCtx(context.Background())
+	require.NoError(t, err)
+	return tx
+}
+
+func TestTransactions(t *testing.T) {
+	t.Run("open up to the maximum", func(t *testing.T) {
+		first, err := Open(beginTx(t), "prest-test", 2)
+		require.NoError(t, err)
+		second, err := Open(beginTx(t), "prest-test", 2)
+		require.NoError(t, err)
+		require.NotEqual(t, first, second)
+		_, err = Open(beginTx(t), "prest-test", 2)
+		require.ErrorIs(t, err, ErrTooMany)
+		require.Equal(t, 2, Count())
+
+		for _, id := range []string{first, second} {
+			tx, err := Acquire(id)
+			require.NoError(t, err)
+			require.NoError(t, tx.Rollback())
+			tx.Release()
+		}
+		require.Equal(t, 0, Count())
+	})
+
+	t.Run("commit", func(t *testing.T) {
+		id, err := Open(beginTx(t), "prest-test", 0)
+		require.NoError(t, err)
+		tx, err := Acquire(id)
+		require.NoError(t, err)
+		require.Equal(t, "prest-test", tx.Database)
+		require.NoError(t, tx.Commit())
+		tx.Release()
+		_, err = Acquire(id)
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("unknown id", func(t *testing.T) {
+		_, err := Acquire("unknown")
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("reap idle transactions", func(t *testing.T) {
+		idle, err := Open(beginTx(t), "prest-test", 0)
+		require.NoError(t, err)
+		busy, err := Open(beginTx(t), "prest-test", 0)
+		require.NoError(t, err)
+		recent, err := Open(beginTx(t), "prest-test", 0)
+		require.NoError(t, err)
+		for _, id := range []string{idle, busy} {
+			OpenTransactions[id].lastUsed = time.Now().Add(-time.Hour)
+		}
+
+		tx, err := Acquire(busy)
+		require.NoError(t, err)
+		require.Equal(t, 1, Reap(time.Minute))
+		_, err = Acquire(idle)
+		require.ErrorIs(t, err, ErrNotFound)
+
+		tx.Release()
+		tx, err = Acquire(recent)
+		require.NoError(t, err)
+		require.NoError(t, tx.Rollback())
+		tx.Release()
+		tx, err = Acquire(busy)
+		require.NoError(t, err)
+		require.NoError(t, tx.Rollback())
+		tx.Release()
+		require.Equal(t, 0, Count())
+	})
+}